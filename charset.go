@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"golang.org/x/net/html/charset"
+	"io"
+	"mime"
+	"strings"
+)
+
+// newFeedDecoder returns an XML decoder for a feed body that produces UTF-8
+// whatever the feed is encoded in. A charset parameter in the Content-Type
+// header, UTF-8 included, takes precedence over the encoding declared in the
+// XML prolog, as RFC 7303 requires; without one (or with one gator doesn't
+// know), a byte order mark or else the prolog decides.
+func newFeedDecoder(body []byte, contentType string) *xml.Decoder {
+	var r io.Reader = bytes.NewReader(body)
+	decided := false
+	for _, label := range []string{headerCharset(contentType), bomCharset(body)} {
+		if label == "" {
+			continue
+		}
+		if cr, err := charset.NewReaderLabel(label, r); err == nil {
+			r, decided = cr, true
+			break
+		}
+	}
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if decided {
+			// Already UTF-8; the prolog's encoding is overridden.
+			return input, nil
+		}
+		return charset.NewReaderLabel(label, input)
+	}
+	return decoder
+}
+
+func headerCharset(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(params["charset"])
+}
+
+// bomCharset names the encoding a byte order mark at the start of body
+// announces, if any.
+func bomCharset(body []byte) string {
+	switch {
+	case bytes.HasPrefix(body, []byte{0xef, 0xbb, 0xbf}):
+		return "utf-8"
+	case bytes.HasPrefix(body, []byte{0xff, 0xfe}):
+		return "utf-16le"
+	case bytes.HasPrefix(body, []byte{0xfe, 0xff}):
+		return "utf-16be"
+	}
+	return ""
+}
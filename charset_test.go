@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFeedDecoder(t *testing.T) {
+	const (
+		latin1  = "Café naïve"
+		cp1252  = "Café – “quoted”"
+		russian = "Привет, мир"
+		kanji   = "日本語のフィード"
+		unicode = "Ünïcode ✓"
+	)
+	tests := []struct {
+		file        string
+		contentType string
+		want        string
+	}{
+		// The prolog (or byte order mark) names the encoding.
+		{"iso-8859-1.xml", "application/rss+xml", latin1},
+		{"windows-1252.xml", "application/xml", cp1252},
+		{"koi8-r.xml", "text/xml", russian},
+		{"shift_jis.xml", "", kanji},
+		{"utf-8.xml", "application/rss+xml", unicode},
+		{"utf-8-bom.xml", "application/rss+xml", unicode},
+		{"utf-16le-bom.xml", "application/rss+xml", unicode},
+
+		// The header agrees with the prolog.
+		{"iso-8859-1.xml", "application/rss+xml; charset=iso-8859-1", latin1},
+		{"koi8-r.xml", "text/xml; charset=KOI8-R", russian},
+		{"utf-8.xml", "application/rss+xml; charset=utf-8", unicode},
+
+		// The header and the prolog disagree: the header wins, whichever
+		// of them says UTF-8.
+		{"windows-1252-as-utf-8.xml", "application/rss+xml; charset=windows-1252", cp1252},
+		{"utf-8-as-iso-8859-1.xml", "application/rss+xml; charset=utf-8", unicode},
+		{"utf-8-as-iso-8859-1.xml", `application/rss+xml; charset="UTF-8"`, unicode},
+		{"iso-8859-1.xml", "text/xml; charset=latin1", latin1},
+
+		// A label gator doesn't know is ignored in favour of the prolog.
+		{"koi8-r.xml", "text/xml; charset=x-unknown", russian},
+	}
+	for _, tt := range tests {
+		body, err := os.ReadFile(filepath.Join("testdata", "charset", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var feed RSSFeed
+		if err := newFeedDecoder(body, tt.contentType).Decode(&feed); err != nil {
+			t.Errorf("%s as %q: %v", tt.file, tt.contentType, err)
+			continue
+		}
+		if feed.Channel.Title != tt.want || len(feed.Channel.Item) != 1 || feed.Channel.Item[0].Title != tt.want {
+			t.Errorf("%s as %q: title %q, want %q", tt.file, tt.contentType, feed.Channel.Title, tt.want)
+		}
+	}
+
+	// Without a header, the prolog is trusted even when it is wrong.
+	body, err := os.ReadFile(filepath.Join("testdata", "charset", "utf-8-as-iso-8859-1.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed RSSFeed
+	if err := newFeedDecoder(body, "application/rss+xml").Decode(&feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.Channel.Title != "ÃœnÃ¯code âœ“" {
+		t.Errorf("prolog ignored without a header charset: title %q", feed.Channel.Title)
+	}
+}
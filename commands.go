@@ -0,0 +1,349 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Exit codes returned by main. Anything not classified below exits with
+// exitFailure.
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitAuth     = 4
+	exitNetwork  = 5
+)
+
+type command struct {
+	name  string
+	args  []string
+	flags *flag.FlagSet
+}
+
+// commandSpec declares a command: its arguments, flags and help text. A spec
+// either has a handler or a set of subcommands (e.g. "token create").
+type commandSpec struct {
+	name    string
+	args    string // synopsis of the positional arguments, e.g. "<title> <url>"
+	summary string
+	group   string
+	minArgs int
+	maxArgs int // -1 means no limit
+	flags   func(fs *flag.FlagSet)
+	listing bool // prints through printListing and so accepts --output
+	handler func(*state, command) error
+	subs    []*commandSpec
+	// complete lists candidates for the next positional argument, for the
+	// shell completion scripts.
+	complete func(*state) ([]string, error)
+}
+
+type commands struct {
+	specs  []*commandSpec
+	byName map[string]*commandSpec
+}
+
+// Help groups, in the order they are printed by `gator help`.
+const (
+	groupUsers  = "USERS"
+	groupFeeds  = "FEEDS"
+	groupRead   = "READING"
+	groupServer = "SERVER"
+	groupUtil   = "UTILITY"
+)
+
+var helpGroups = []string{groupUsers, groupFeeds, groupRead, groupServer, groupUtil}
+
+// cliError attaches an exit code to an error.
+type cliError struct {
+	code int
+	err  error
+}
+
+func (e *cliError) Error() string { return e.err.Error() }
+func (e *cliError) Unwrap() error { return e.err }
+
+func usageError(format string, a ...any) error {
+	return &cliError{exitUsage, fmt.Errorf(format, a...)}
+}
+
+func notFoundError(format string, a ...any) error {
+	return &cliError{exitNotFound, fmt.Errorf(format, a...)}
+}
+
+func authError(format string, a ...any) error {
+	return &cliError{exitAuth, fmt.Errorf(format, a...)}
+}
+
+// exitCode maps an error returned by a handler to the process exit code.
+func exitCode(err error) int {
+	var cerr *cliError
+	var urlErr *url.Error
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &cerr):
+		return cerr.code
+	case errors.Is(err, sql.ErrNoRows):
+		return exitNotFound
+	case errors.Is(err, errWrongPassword), errors.Is(err, errInvalidToken), errors.Is(err, errTokenExpired):
+		return exitAuth
+	case errors.As(err, &urlErr), errors.As(err, &netErr):
+		return exitNetwork
+	}
+	return exitFailure
+}
+
+func (c *commands) register(spec *commandSpec) {
+	if c.byName == nil {
+		c.byName = make(map[string]*commandSpec)
+	}
+	c.specs = append(c.specs, spec)
+	c.byName[spec.name] = spec
+}
+
+// names returns the registered command names, sorted.
+func (c *commands) names() []string {
+	names := make([]string, 0, len(c.byName))
+	for name := range c.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// run resolves args (the command line without the program name) to a spec,
+// validates flags and arguments and calls the handler.
+func (c *commands) run(s *state, args []string) error {
+	if len(args) == 0 {
+		c.printHelp(os.Stdout)
+		return nil
+	}
+	spec, ok := c.byName[args[0]]
+	if !ok {
+		return usageError("unknown command %q - run \"gator help\" for a list", args[0])
+	}
+	path, args := []string{spec.name}, args[1:]
+	for len(spec.subs) > 0 {
+		if len(args) == 0 {
+			return usageError("%s needs a subcommand: %s", strings.Join(path, " "), subNames(spec))
+		}
+		sub := findSub(spec, args[0])
+		if sub == nil {
+			return usageError("unknown %s subcommand %q (use %s)", strings.Join(path, " "), args[0], subNames(spec))
+		}
+		spec, path, args = sub, append(path, sub.name), args[1:]
+	}
+
+	name := strings.Join(path, " ")
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	spec.defineFlags(fs, &s.output)
+	// Flags may come before, between or after the arguments ("--" ends
+	// them); commands without flags take their arguments verbatim unless
+	// the first one asks for help.
+	var rest []string
+	for spec.hasFlags() || len(args) > 0 && isHelpFlag(args[0]) {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				printCommandHelp(os.Stdout, path, spec)
+				return nil
+			}
+			return usageError("%v\nusage: %s", err, synopsis(path, spec))
+		}
+		remaining := fs.Args()
+		if n := len(args) - len(remaining); (n > 0 && args[n-1] == "--") || len(remaining) == 0 {
+			args = remaining
+			break
+		}
+		rest = append(rest, remaining[0])
+		args = remaining[1:]
+	}
+	rest = append(rest, args...)
+	if len(rest) < spec.minArgs || (spec.maxArgs >= 0 && len(rest) > spec.maxArgs) {
+		return usageError("usage: %s", synopsis(path, spec))
+	}
+	return spec.handler(s, command{name: name, args: rest, flags: fs})
+}
+
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+func (spec *commandSpec) hasFlags() bool {
+	return spec.flags != nil || spec.listing
+}
+
+// defineFlags adds the spec's flags to fs; a listing's --output writes into
+// output.
+func (spec *commandSpec) defineFlags(fs *flag.FlagSet, output *string) {
+	if spec.flags != nil {
+		spec.flags(fs)
+	}
+	if spec.listing {
+		outputFlags(fs, output)
+	}
+}
+
+func findSub(spec *commandSpec, name string) *commandSpec {
+	for _, sub := range spec.subs {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func subNames(spec *commandSpec) string {
+	names := make([]string, 0, len(spec.subs))
+	for _, sub := range spec.subs {
+		names = append(names, sub.name)
+	}
+	return strings.Join(names, "|")
+}
+
+func synopsis(path []string, spec *commandSpec) string {
+	parts := append([]string{"gator"}, path...)
+	if len(spec.subs) > 0 {
+		parts = append(parts, subNames(spec))
+	}
+	if spec.hasFlags() {
+		parts = append(parts, "[flags]")
+	}
+	if spec.args != "" {
+		parts = append(parts, spec.args)
+	}
+	return strings.Join(parts, " ")
+}
+
+func printCommandHelp(w io.Writer, path []string, spec *commandSpec) {
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", synopsis(path, spec), spec.summary)
+	if len(spec.subs) > 0 {
+		fmt.Fprintln(w, "\nSubcommands:")
+		for _, sub := range spec.subs {
+			fmt.Fprintf(w, "    %-28s %s\n", strings.TrimSpace(sub.name+" "+sub.args), sub.summary)
+		}
+	}
+	if spec.hasFlags() {
+		format := outputText
+		fs := flag.NewFlagSet(spec.name, flag.ContinueOnError)
+		spec.defineFlags(fs, &format)
+		fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
+
+func (c *commands) printHelp(w io.Writer) {
+	fmt.Fprint(w, `Gator – a tiny console RSS reader
+
+USAGE:
+    gator [--output text|json|csv|tsv|table] <command> [arguments]
+
+    --output (or -o) changes how the listing commands (users, feeds,
+    following, browse, posts, podcast list, token list) print their results.
+    It may also be given as a flag of the command itself; "--" ends flags.
+`)
+	for _, group := range helpGroups {
+		fmt.Fprintf(w, "\n%s\n", group)
+		for _, spec := range c.specs {
+			if spec.group != group {
+				continue
+			}
+			if len(spec.subs) == 0 {
+				fmt.Fprintf(w, "    %-28s %s\n", strings.TrimSpace(spec.name+" "+spec.args), spec.summary)
+				continue
+			}
+			for _, sub := range spec.subs {
+				fmt.Fprintf(w, "    %-28s %s\n", strings.TrimSpace(spec.name+" "+sub.name+" "+sub.args), sub.summary)
+			}
+		}
+	}
+	fmt.Fprintf(w, `
+Run "gator help <command>" for the flags a command accepts.
+
+EXIT CODES
+    %d  success            %d  usage error        %d  not found
+    %d  authentication     %d  network failure    %d  any other failure
+
+EXAMPLES
+    gator register alice
+    gator login alice
+    gator addfeed "Hacker News" https://news.ycombinator.com/rss
+    gator agg 1m
+    gator browse --limit=10 --sort=time --page=2
+`, exitOK, exitUsage, exitNotFound, exitAuth, exitNetwork, exitFailure)
+}
+
+// handlerHelp is registered by main as a closure over the command registry.
+func (c *commands) handlerHelp(s *state, cmd command) error {
+	if len(cmd.args) == 0 {
+		c.printHelp(os.Stdout)
+		return nil
+	}
+	spec, ok := c.byName[cmd.args[0]]
+	if !ok {
+		return usageError("unknown command %q", cmd.args[0])
+	}
+	path := []string{spec.name}
+	for _, arg := range cmd.args[1:] {
+		sub := findSub(spec, arg)
+		if sub == nil {
+			break
+		}
+		spec, path = sub, append(path, sub.name)
+	}
+	printCommandHelp(os.Stdout, path, spec)
+	return nil
+}
+
+func (c command) stringFlag(name string) string {
+	return c.flags.Lookup(name).Value.(flag.Getter).Get().(string)
+}
+
+func (c command) intFlag(name string) int {
+	return c.flags.Lookup(name).Value.(flag.Getter).Get().(int)
+}
+
+func (c command) boolFlag(name string) bool {
+	return c.flags.Lookup(name).Value.(flag.Getter).Get().(bool)
+}
+
+func (c command) durationFlag(name string) time.Duration {
+	return c.flags.Lookup(name).Value.(flag.Getter).Get().(time.Duration)
+}
+
+func (c command) stringsFlag(name string) []string {
+	return c.flags.Lookup(name).Value.(flag.Getter).Get().([]string)
+}
+
+// stringsValue is a flag that may be given several times.
+type stringsValue []string
+
+func (v *stringsValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return strings.Join(*v, ", ")
+}
+
+func (v *stringsValue) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
+
+func (v *stringsValue) Get() any {
+	return []string(*v)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"gator/internal/config"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCommandsRun(t *testing.T) {
+	type call struct {
+		name  string
+		args  []string
+		limit int
+		all   bool
+	}
+	var got *call
+	handler := func(s *state, cmd command) error {
+		got = &call{name: cmd.name, args: cmd.args}
+		if cmd.flags.Lookup("limit") != nil {
+			got.limit, got.all = cmd.intFlag("limit"), cmd.boolFlag("all")
+		}
+		return nil
+	}
+	c := &commands{}
+	c.register(&commandSpec{name: "add", minArgs: 1, maxArgs: 2, handler: handler, flags: func(fs *flag.FlagSet) {
+		fs.Int("limit", 10, "")
+		fs.Bool("all", false, "")
+	}})
+	c.register(&commandSpec{name: "say", maxArgs: -1, handler: handler})
+	c.register(&commandSpec{name: "token", subs: []*commandSpec{
+		{name: "revoke", minArgs: 1, maxArgs: 1, handler: handler},
+	}})
+
+	tests := []struct {
+		args     []string
+		want     *call // nil: the handler must not run
+		wantCode int
+	}{
+		{args: []string{"add", "a"}, want: &call{"add", []string{"a"}, 10, false}},
+		{args: []string{"add", "--limit=3", "a", "b"}, want: &call{"add", []string{"a", "b"}, 3, false}},
+		{args: []string{"add", "a", "--limit", "3", "b"}, want: &call{"add", []string{"a", "b"}, 3, false}},
+		{args: []string{"add", "a", "b", "-all"}, want: &call{"add", []string{"a", "b"}, 10, true}},
+		{args: []string{"add", "a", "--all", "b", "--limit=5"}, want: &call{"add", []string{"a", "b"}, 5, true}},
+		// "--" ends the flags, wherever it is.
+		{args: []string{"add", "--", "--limit=3"}, want: &call{"add", []string{"--limit=3"}, 10, false}},
+		{args: []string{"add", "a", "--", "-all"}, want: &call{"add", []string{"a", "-all"}, 10, false}},
+		{args: []string{"add", "--all", "--", "--"}, want: &call{"add", []string{"--"}, 10, true}},
+		{args: []string{"add", "-h"}},
+		{args: []string{"add", "a", "--help"}},
+		{args: []string{"add", "--verbose", "a"}, wantCode: exitUsage},
+		{args: []string{"add", "--limit=many", "a"}, wantCode: exitUsage},
+		{args: []string{"add"}, wantCode: exitUsage},
+		{args: []string{"add", "a", "b", "c"}, wantCode: exitUsage},
+		{args: []string{"add", "a", "--", "b", "c"}, wantCode: exitUsage},
+		// Commands without flags take their arguments as they are, unless
+		// the first asks for help.
+		{args: []string{"say", "-n", "--x=1", "--"}, want: &call{"say", []string{"-n", "--x=1", "--"}, 0, false}},
+		{args: []string{"say", "hi", "-h"}, want: &call{"say", []string{"hi", "-h"}, 0, false}},
+		{args: []string{"say", "-h"}},
+		{args: []string{"say", "--help", "hi"}},
+		{args: []string{"token", "revoke", "abcd"}, want: &call{"token revoke", []string{"abcd"}, 0, false}},
+		{args: []string{"token", "revoke", "-h"}},
+		{args: []string{"token"}, wantCode: exitUsage},
+		{args: []string{"token", "burn", "abcd"}, wantCode: exitUsage},
+		{args: []string{"unknown"}, wantCode: exitUsage},
+	}
+	for _, tt := range tests {
+		got = nil
+		err := c.run(&state{output: outputText}, tt.args)
+		if code := exitCode(err); code != tt.wantCode {
+			t.Errorf("run(%q): exit code %d (%v), want %d", tt.args, code, err, tt.wantCode)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("run(%q) called %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	// A real network failure, as fetch reports it.
+	srv := httptest.NewServer(nil)
+	closedURL := srv.URL
+	srv.Close()
+	_, _, fetchErr := newTestHTTPClient(t, config.HTTPConfig{}).fetch(context.Background(), closedURL, "", nil)
+	if fetchErr == nil {
+		t.Fatal("fetch from a closed server succeeded")
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"plain error", errors.New("boom"), exitFailure},
+		{"HTTP status", &httpStatusError{URL: "https://example.com", StatusCode: 500, Status: "500 Internal Server Error"}, exitFailure},
+		{"usage", usageError("bad %s", "flag"), exitUsage},
+		{"not found", notFoundError("no feed"), exitNotFound},
+		{"wrapped not found", fmt.Errorf("following: %w", notFoundError("no feed")), exitNotFound},
+		{"no rows", fmt.Errorf("failed to get user: %w", sql.ErrNoRows), exitNotFound},
+		{"auth", authError("not yours"), exitAuth},
+		{"wrong password", errWrongPassword, exitAuth},
+		{"invalid token", fmt.Errorf("login: %w", errInvalidToken), exitAuth},
+		{"expired token", errTokenExpired, exitAuth},
+		{"fetch", fmt.Errorf("failed to fetch feed: %w", fetchErr), exitNetwork},
+		{"DNS", &net.DNSError{Err: "no such host", Name: "feeds.invalid"}, exitNetwork},
+		{"usage beats a wrapped network error", usageError("bad: %w", fetchErr), exitUsage},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
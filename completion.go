@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The generated scripts complete command names from the registry at the time
+// the script is printed. Everything after the command name (subcommands,
+// users, feeds) is looked up at completion time through the hidden
+// "gator __complete <words...>" command, so it stays current.
+
+const bashCompletion = `# bash completion for gator
+# Load with: source <(gator completion bash)
+_gator() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur"))
+        return
+    fi
+    COMPREPLY=($(compgen -W "$(gator __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" 2>/dev/null)" -- "$cur"))
+}
+complete -F _gator gator
+`
+
+const zshCompletion = `#compdef gator
+# zsh completion for gator
+# Load with: source <(gator completion zsh)
+_gator() {
+    local -a candidates
+    if (( CURRENT == 2 )); then
+        candidates=(%s)
+        _describe 'command' candidates
+        return
+    fi
+    candidates=("${(@f)$(gator __complete "${(@)words[2,CURRENT-1]}" 2>/dev/null)}")
+    compadd -a candidates
+}
+compdef _gator gator
+`
+
+const fishCompletion = `# fish completion for gator
+# Load with: gator completion fish | source
+function __gator_complete
+    set -l words (commandline -opc)
+    gator __complete $words[2..-1] 2>/dev/null
+end
+complete -c gator -f
+%scomplete -c gator -n 'not __fish_use_subcommand' -a '(__gator_complete)'
+`
+
+func (c *commands) handlerCompletion(s *state, cmd command) error {
+	var visible []*commandSpec
+	for _, name := range c.names() {
+		if spec := c.byName[name]; spec.group != "" {
+			visible = append(visible, spec)
+		}
+	}
+
+	switch cmd.args[0] {
+	case "bash":
+		names := make([]string, len(visible))
+		for i, spec := range visible {
+			names[i] = spec.name
+		}
+		fmt.Printf(bashCompletion, strings.Join(names, "\n"))
+	case "zsh":
+		described := make([]string, len(visible))
+		for i, spec := range visible {
+			described[i] = shellQuote(spec.name + ":" + spec.summary)
+		}
+		fmt.Printf(zshCompletion, strings.Join(described, " "))
+	case "fish":
+		var b strings.Builder
+		for _, spec := range visible {
+			fmt.Fprintf(&b, "complete -c gator -n '__fish_use_subcommand' -a %s -d %s\n",
+				shellQuote(spec.name), shellQuote(spec.summary))
+		}
+		fmt.Printf(fishCompletion, b.String())
+	default:
+		return usageError("unsupported shell %q (use bash, zsh or fish)", cmd.args[0])
+	}
+	return nil
+}
+
+// shellQuote single-quotes s for bash, zsh and fish alike.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// handlerComplete prints one candidate per line for the word following
+// cmd.args, which are the words already typed after "gator". It is called by
+// the completion scripts and never fails loudly: a shell prompt is no place
+// for error messages.
+func (c *commands) handlerComplete(s *state, cmd command) error {
+	if len(cmd.args) == 0 {
+		for _, name := range c.names() {
+			if c.byName[name].group != "" {
+				fmt.Println(name)
+			}
+		}
+		return nil
+	}
+	spec, ok := c.byName[cmd.args[0]]
+	if !ok {
+		return nil
+	}
+	rest := cmd.args[1:]
+	for len(spec.subs) > 0 {
+		if len(rest) == 0 {
+			for _, sub := range spec.subs {
+				fmt.Println(sub.name)
+			}
+			return nil
+		}
+		spec = findSub(spec, rest[0])
+		if spec == nil {
+			return nil
+		}
+		rest = rest[1:]
+	}
+
+	positional := 0
+	for _, arg := range rest {
+		if !strings.HasPrefix(arg, "-") {
+			positional++
+		}
+	}
+	if spec.complete == nil || (spec.maxArgs >= 0 && positional >= spec.maxArgs) {
+		return nil
+	}
+	candidates, err := spec.complete(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+	for _, candidate := range candidates {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+func completeUsers(s *state) ([]string, error) {
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(users))
+	for i, user := range users {
+		names[i] = user.Name
+	}
+	return names, nil
+}
+
+// completeFeeds lists every feed by URL and name.
+func completeFeeds(s *state) ([]string, error) {
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]string, 0, 2*len(feeds))
+	for _, feed := range feeds {
+		refs = append(refs, feed.Url, feed.Name)
+	}
+	return refs, nil
+}
+
+// completeFollowing lists the current user's feeds by URL and name; it yields
+// nothing when nobody is logged in.
+func completeFollowing(s *state) ([]string, error) {
+	if s.CurrentUser == "" {
+		return nil, nil
+	}
+	ctx := context.Background()
+	user, err := s.db.GetUser(ctx, s.CurrentUser)
+	if err != nil {
+		return nil, err
+	}
+	follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]string, 0, 2*len(follows))
+	for _, follow := range follows {
+		refs = append(refs, follow.FeedUrl, follow.FeedName)
+	}
+	return refs, nil
+}
+
+// completeCommands completes "gator help <command>".
+func (c *commands) completeCommands(s *state) ([]string, error) {
+	var names []string
+	for _, name := range c.names() {
+		if c.byName[name].group != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
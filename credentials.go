@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// feedCredentials is what a feed's fetches are authenticated with. It is
+// stored sealed with the config file's credentials key and never printed.
+type feedCredentials struct {
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	Headers  []string `json:"headers,omitempty"` // "Name: value"
+	Cookies  []string `json:"cookies,omitempty"` // "name=value"
+}
+
+func (c feedCredentials) header() http.Header {
+	header := http.Header{}
+	for _, h := range c.Headers {
+		name, value, _ := strings.Cut(h, ":")
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if c.Username != "" || c.Password != "" {
+		req := &http.Request{Header: header}
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	if len(c.Cookies) > 0 {
+		header.Set("Cookie", strings.Join(c.Cookies, "; "))
+	}
+	return header
+}
+
+// errNoCredentialsKey means feed credentials are stored but the config file
+// in use - which is per user account - lacks the key they were sealed with.
+var errNoCredentialsKey = errors.New("no credentials_key in the config file - it is needed to decrypt feed credentials; " +
+	"copy it from the config file of the account that ran \"gator feed auth\"")
+
+// credentialsCipher returns the AEAD feed credentials are sealed with,
+// generating and saving the key first if create is set and there is none.
+func credentialsCipher(s *state, create bool) (cipher.AEAD, error) {
+	if s.CredentialsKey == "" {
+		if !create {
+			return nil, errNoCredentialsKey
+		}
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := s.SetCredentialsKey(base64.StdEncoding.EncodeToString(key)); err != nil {
+			return nil, fmt.Errorf("failed to save credentials key: %w", err)
+		}
+	}
+	key, err := base64.StdEncoding.DecodeString(s.CredentialsKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("invalid credentials_key in the config file")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealCredentials encrypts creds for feedID; the ID is authenticated too,
+// so a sealed value can't be moved to another feed.
+func sealCredentials(s *state, feedID uuid.UUID, creds feedCredentials) ([]byte, error) {
+	aead, err := credentialsCipher(s, true)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(creds)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, feedID[:]), nil
+}
+
+func openCredentials(s *state, feedID uuid.UUID, sealed []byte) (feedCredentials, error) {
+	aead, err := credentialsCipher(s, false)
+	if err != nil {
+		return feedCredentials{}, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return feedCredentials{}, errors.New("stored credentials are corrupt")
+	}
+	nonce, box := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, box, feedID[:])
+	if err != nil {
+		return feedCredentials{}, errors.New("stored credentials can't be decrypted with this credentials_key")
+	}
+	var creds feedCredentials
+	err = json.Unmarshal(plain, &creds)
+	return creds, err
+}
+
+func getFeedCredentials(ctx context.Context, s *state, feedID uuid.UUID) (feedCredentials, bool, error) {
+	row, err := s.db.GetFeedCredentials(ctx, feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return feedCredentials{}, false, nil
+	} else if err != nil {
+		return feedCredentials{}, false, err
+	}
+	creds, err := openCredentials(s, feedID, row.Secret)
+	return creds, true, err
+}
+
+// feedRequestHeader is the extra header to fetch a feed with: nil unless
+// credentials were set with "gator feed auth".
+func feedRequestHeader(ctx context.Context, s *state, feedID uuid.UUID) (http.Header, error) {
+	creds, ok, err := getFeedCredentials(ctx, s, feedID)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return creds.header(), nil
+}
+
+// sameHostHeader is header, a feed's credentials, if rawURL is on the same
+// host as the feed, and nil otherwise: articles and episodes on other sites
+// never see them.
+func sameHostHeader(header http.Header, feedURL, rawURL string) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	feed, err := url.Parse(feedURL)
+	if err != nil {
+		return nil
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(feed.Host, target.Host) {
+		return nil
+	}
+	return header
+}
+
+// credentialFlags defines the flags credentials are given with, for
+// "feed auth" and "addfeed".
+func credentialFlags(fs *flag.FlagSet) {
+	fs.String("basic", "", "HTTP basic auth as user:password")
+	fs.Var(&stringsValue{}, "header", `extra request header as "Name: value" (repeatable)`)
+	fs.Var(&stringsValue{}, "cookie", `cookie as name=value (repeatable)`)
+}
+
+// credentialsFromFlags reads the flags defined by credentialFlags; ok is
+// false if none were given.
+func credentialsFromFlags(cmd command) (creds feedCredentials, ok bool, err error) {
+	basic, headers, cookies := cmd.stringFlag("basic"), cmd.stringsFlag("header"), cmd.stringsFlag("cookie")
+	if basic == "" && len(headers) == 0 && len(cookies) == 0 {
+		return creds, false, nil
+	}
+	if basic != "" {
+		username, password, ok := strings.Cut(basic, ":")
+		if !ok {
+			return creds, false, usageError("--basic must be user:password")
+		}
+		creds.Username, creds.Password = username, password
+	}
+	for _, h := range headers {
+		name, _, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return creds, false, usageError(`invalid --header %q (use "Name: value")`, h)
+		}
+		creds.Headers = append(creds.Headers, h)
+	}
+	for _, c := range cookies {
+		if name, _, ok := strings.Cut(c, "="); !ok || strings.TrimSpace(name) == "" {
+			return creds, false, usageError("invalid --cookie %q (use name=value)", c)
+		}
+		creds.Cookies = append(creds.Cookies, strings.TrimSpace(c))
+	}
+	return creds, true, nil
+}
+
+// storeFeedCredentials seals and saves creds as the feed's credentials,
+// replacing any it had.
+func storeFeedCredentials(ctx context.Context, s *state, feed database.Feed, creds feedCredentials) error {
+	sealed, err := sealCredentials(s, feed.ID, creds)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+	err = s.db.SetFeedCredentials(ctx, database.SetFeedCredentialsParams{
+		FeedID: feed.ID,
+		Secret: sealed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store credentials: %w", err)
+	}
+	fmt.Printf("Stored credentials for %s:\n", feed.Name)
+	for _, line := range describeCredentials(creds) {
+		fmt.Printf("  %s\n", line)
+	}
+	return nil
+}
+
+func feedAuthFlags(fs *flag.FlagSet) {
+	credentialFlags(fs)
+	fs.Bool("clear", false, "remove the feed's credentials")
+}
+
+// checkFeedCreator returns an authError unless user added feed. Settings
+// that every follower shares are reserved to the feed's creator; action
+// says what was refused.
+func checkFeedCreator(ctx context.Context, s *state, feed database.Feed, user database.User, action string) error {
+	if feed.UserID == user.ID {
+		return nil
+	}
+	creator, err := s.db.GetUserName(ctx, feed.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get the user who added %s: %w", feed.Name, err)
+	}
+	return authError("only %s, who added %s, can %s", creator, feed.Name, action)
+}
+
+// handlerFeedAuth replaces a feed's credentials with those given, removes
+// them with --clear, or without flags describes what is set - header and
+// cookie names only, never values. Credentials are shared by everyone
+// following the feed, so only the user who added it may manage them.
+func handlerFeedAuth(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	feeds, err := s.db.GetFeedsForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get followed feeds: %w", err)
+	}
+	feed, err := resolveFeed(feeds, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if err := checkFeedCreator(ctx, s, feed, user, "manage its credentials"); err != nil {
+		return err
+	}
+
+	creds, set, err := credentialsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	switch {
+	case cmd.boolFlag("clear") && set:
+		return usageError("--clear can't be combined with other flags")
+	case cmd.boolFlag("clear"):
+		if err := s.db.DeleteFeedCredentials(ctx, feed.ID); err != nil {
+			return fmt.Errorf("failed to remove credentials: %w", err)
+		}
+		fmt.Printf("Removed credentials for %s\n", feed.Name)
+		return nil
+	case !set:
+		creds, ok, err := getFeedCredentials(ctx, s, feed.ID)
+		if err != nil {
+			return fmt.Errorf("failed to read credentials: %w", err)
+		}
+		if !ok {
+			fmt.Printf("%s has no credentials\n", feed.Name)
+			return nil
+		}
+		fmt.Printf("%s is fetched with:\n", feed.Name)
+		for _, line := range describeCredentials(creds) {
+			fmt.Printf("  %s\n", line)
+		}
+		return nil
+	}
+	return storeFeedCredentials(ctx, s, feed, creds)
+}
+
+func describeCredentials(c feedCredentials) []string {
+	var lines []string
+	if c.Username != "" || c.Password != "" {
+		lines = append(lines, fmt.Sprintf("basic auth as %s", c.Username))
+	}
+	var names []string
+	for _, h := range c.Headers {
+		name, _, _ := strings.Cut(h, ":")
+		names = append(names, textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name)))
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		lines = append(lines, "headers: "+strings.Join(names, ", "))
+	}
+	names = names[:0]
+	for _, c := range c.Cookies {
+		name, _, _ := strings.Cut(c, "=")
+		names = append(names, strings.TrimSpace(name))
+	}
+	if len(names) > 0 {
+		lines = append(lines, "cookies: "+strings.Join(names, ", "))
+	}
+	return lines
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSameHostHeader(t *testing.T) {
+	header := http.Header{"Authorization": {"Basic bWU6c2VjcmV0"}}
+	tests := []struct {
+		feedURL, rawURL string
+		want            http.Header
+	}{
+		{"https://intranet.example/feed.xml", "https://intranet.example/posts/1", header},
+		{"https://intranet.example/feed.xml", "https://INTRANET.example/episode.mp3", header},
+		{"https://intranet.example/feed.xml", "https://cdn.example/episode.mp3", nil},
+		{"https://intranet.example/feed.xml", "https://intranet.example:8443/posts/1", nil},
+		{"https://intranet.example/feed.xml", "::not a url", nil},
+	}
+	for _, tt := range tests {
+		if got := sameHostHeader(header, tt.feedURL, tt.rawURL); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sameHostHeader(%q, %q) = %v, want %v", tt.feedURL, tt.rawURL, got, tt.want)
+		}
+	}
+	if got := sameHostHeader(nil, "https://a.example/", "https://a.example/x"); got != nil {
+		t.Errorf("no credentials: got %v", got)
+	}
+}
+
+func TestFeedAuthOnlyByCreator(t *testing.T) {
+	db := newMemStore()
+	alice, bob := db.addUser("alice"), db.addUser("bob")
+	feed := db.addFeed(alice, "Intranet", "https://intranet.example/feed.xml")
+	db.follow(bob, feed)
+	s := newTestState(db)
+
+	fs := flag.NewFlagSet("feed auth", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	feedAuthFlags(fs)
+	if err := fs.Parse([]string{"--clear"}); err != nil {
+		t.Fatal(err)
+	}
+	err := handlerFeedAuth(s, command{name: "feed auth", args: []string{"intranet"}, flags: fs}, bob)
+	if exitCode(err) != exitAuth {
+		t.Fatalf("a follower changed the credentials: %v", err)
+	}
+}
+
+func TestFeedFullContentOnlyByCreator(t *testing.T) {
+	db := newMemStore()
+	alice, bob := db.addUser("alice"), db.addUser("bob")
+	feed := db.addFeed(alice, "Intranet", "https://intranet.example/feed.xml")
+	db.follow(bob, feed)
+	s := newTestState(db)
+
+	err := handlerFeedFullContent(s, command{name: "full-content", args: []string{"intranet", "on"}}, bob)
+	if exitCode(err) != exitAuth {
+		t.Fatalf("a follower changed the full-content setting: %v", err)
+	}
+	if err := handlerFeedFullContent(s, command{name: "full-content", args: []string{"intranet", "on"}}, alice); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := db.GetFeedByID(context.Background(), feed.ID); !got.FetchFullContent {
+		t.Error("the creator's setting wasn't stored")
+	}
+}
+
+func TestCredentialsHeader(t *testing.T) {
+	creds := feedCredentials{
+		Username: "me",
+		Password: "secret",
+		Headers:  []string{"X-Api-Key: abc", "x-team:  blue "},
+		Cookies:  []string{"session=xyz", "theme=dark"},
+	}
+	want := http.Header{
+		"Authorization": {"Basic bWU6c2VjcmV0"},
+		"X-Api-Key":     {"abc"},
+		"X-Team":        {"blue"},
+		"Cookie":        {"session=xyz; theme=dark"},
+	}
+	if got := creds.header(); !reflect.DeepEqual(got, want) {
+		t.Errorf("header() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// The <link rel="alternate"> types that announce a feed. Only RSS can be
+// read; Atom and JSON Feed candidates are still collected so that a site
+// offering only those is reported as such rather than as having no feed.
+const (
+	feedMIMEType     = "application/rss+xml"
+	atomMIMEType     = "application/atom+xml"
+	jsonFeedMIMEType = "application/feed+json"
+)
+
+// feedFormats names the feed types discovery recognizes.
+var feedFormats = map[string]string{
+	feedMIMEType:     "RSS",
+	atomMIMEType:     "Atom",
+	jsonFeedMIMEType: "JSON Feed",
+}
+
+// commonFeedPaths are tried on the site's root when a page announces no RSS
+// feed.
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/index.xml", "/feed.xml", "/rss", "/atom.xml"}
+
+type feedCandidate struct {
+	URL   string
+	Title string
+	Type  string
+}
+
+// discoverFeeds looks for feeds belonging to the web page at pageURL: first
+// the ones it announces with <link rel="alternate">, then, if none of those
+// is RSS, well-known feed paths on the same site. Atom and JSON feeds are
+// returned too; splitCandidates sets them apart. header, if any, is sent to
+// pageURL's host only.
+func discoverFeeds(ctx context.Context, client *httpClient, pageURL string, header http.Header) ([]feedCandidate, error) {
+	body, res, err := client.fetch(ctx, pageURL, "text/html,application/xhtml+xml", header)
+	if err != nil {
+		return nil, err
+	}
+	base := res.Request.URL
+	candidates := feedLinks(body, base)
+	if slices.ContainsFunc(candidates, feedCandidate.readable) {
+		return candidates, nil
+	}
+
+	for _, p := range commonFeedPaths {
+		probe := base.ResolveReference(&url.URL{Path: p})
+		body, res, err := client.fetch(ctx, probe.String(), "", sameHostHeader(header, pageURL, probe.String()))
+		if err != nil {
+			continue
+		}
+		contentType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+		found := res.Request.URL.String()
+		typ := sniffFeedType(body, contentType)
+		if typ == "" || slices.ContainsFunc(candidates, func(c feedCandidate) bool { return c.URL == found }) {
+			continue
+		}
+		candidates = append(candidates, feedCandidate{URL: found, Type: typ})
+	}
+	return candidates, nil
+}
+
+// readable reports whether gator can read the candidate: only RSS.
+func (c feedCandidate) readable() bool {
+	return c.Type == feedMIMEType
+}
+
+// splitCandidates separates the feeds gator can read from the Atom and JSON
+// feeds it can't.
+func splitCandidates(all []feedCandidate) (readable, skipped []feedCandidate) {
+	for _, c := range all {
+		if c.readable() {
+			readable = append(readable, c)
+		} else {
+			skipped = append(skipped, c)
+		}
+	}
+	return readable, skipped
+}
+
+// describeSkipped lists skipped candidates as "URL (Atom), URL (JSON Feed)".
+func describeSkipped(skipped []feedCandidate) string {
+	parts := make([]string, len(skipped))
+	for i, c := range skipped {
+		parts[i] = fmt.Sprintf("%s (%s)", c.URL, feedFormats[c.Type])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// feedLinks returns the feeds a page announces in <link rel="alternate">
+// tags, with their URLs made absolute.
+func feedLinks(page []byte, base *url.URL) []feedCandidate {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil
+	}
+	var candidates []feedCandidate
+	seen := map[string]bool{}
+	walkElements(doc, func(n *html.Node) {
+		if n.DataAtom != atom.Link {
+			return
+		}
+		rels := strings.Fields(strings.ToLower(attr(n, "rel")))
+		typ := strings.ToLower(strings.TrimSpace(attr(n, "type")))
+		href := strings.TrimSpace(attr(n, "href"))
+		if _, ok := feedFormats[typ]; href == "" || !ok || !containsString(rels, "alternate") {
+			return
+		}
+		u, err := base.Parse(href)
+		if err != nil || seen[u.String()] {
+			return
+		}
+		seen[u.String()] = true
+		candidates = append(candidates, feedCandidate{URL: u.String(), Title: strings.TrimSpace(attr(n, "title")), Type: typ})
+	})
+	return candidates
+}
+
+// sniffFeedType tells RSS, Atom and JSON feeds apart from each other and
+// from the HTML error or landing page many sites serve on unknown paths. It
+// returns the feed's MIME type, or "" if body isn't a feed.
+func sniffFeedType(body []byte, contentType string) string {
+	if _, ok := feedFormats[contentType]; ok {
+		return contentType
+	}
+	head := strings.ToLower(string(bytes.TrimSpace(body[:min(len(body), 512)])))
+	head = strings.TrimPrefix(head, "\ufeff")
+	if strings.HasPrefix(head, "{") {
+		if strings.Contains(head, "jsonfeed.org/version") {
+			return jsonFeedMIMEType
+		}
+		return ""
+	}
+	// Past an XML declaration, comments and stylesheets may come before the
+	// root element; without one, the document must start with it.
+	hasRoot := strings.HasPrefix
+	if strings.HasPrefix(head, "<?xml") {
+		hasRoot = strings.Contains
+	}
+	switch {
+	case hasRoot(head, "<rss"):
+		return feedMIMEType
+	case hasRoot(head, "<feed"):
+		return atomMIMEType
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
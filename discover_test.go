@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"gator/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFeedOnlyReadsRSS(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{"rss", `<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title><item><title>Post</title></item></channel></rss>`, ""},
+		{"empty channel", `<rss version="2.0"><channel></channel></rss>`, ""},
+		{"atom", `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title></feed>`, "<feed>"},
+		{"rss 1.0", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><channel><title>Blog</title></channel></rdf:RDF>`, "<RDF>"},
+		{"no channel", `<rss version="2.0"><item><title>Post</title></item></rss>`, "no <channel>"},
+		{"json feed", `{"version": "https://jsonfeed.org/version/1.1", "title": "Blog"}`, "EOF"},
+	}
+	for _, tt := range tests {
+		feed, err := parseFeed([]byte(tt.doc), "application/xml")
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error %v, want one mentioning %q (feed %+v)", tt.name, err, tt.wantErr, feed)
+		}
+	}
+}
+
+func TestFeedLinks(t *testing.T) {
+	page := `<html><head>
+<link rel="alternate" type="application/atom+xml" href="/atom.xml" title="Atom">
+<link rel="alternate" type="application/feed+json" href="/feed.json" title="JSON">
+<link rel="alternate" type="application/rss+xml" href="/rss.xml" title="RSS">
+<link rel="alternate" type="application/rss+xml" href="/rss.xml" title="Duplicate">
+<link rel="stylesheet" type="application/rss+xml" href="/not-a-feed.xml">
+<link rel="alternate" type="text/html" href="/fr/">
+</head></html>`
+	base, _ := url.Parse("https://example.com/blog/")
+	got := feedLinks([]byte(page), base)
+	want := []feedCandidate{
+		{URL: "https://example.com/atom.xml", Title: "Atom", Type: atomMIMEType},
+		{URL: "https://example.com/feed.json", Title: "JSON", Type: jsonFeedMIMEType},
+		{URL: "https://example.com/rss.xml", Title: "RSS", Type: feedMIMEType},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("feedLinks = %+v, want %+v", got, want)
+	}
+	readable, skipped := splitCandidates(got)
+	if !reflect.DeepEqual(readable, want[2:]) || !reflect.DeepEqual(skipped, want[:2]) {
+		t.Errorf("splitCandidates = %+v, %+v", readable, skipped)
+	}
+}
+
+func TestSniffFeedType(t *testing.T) {
+	tests := []struct {
+		body, contentType string
+		want              string
+	}{
+		{`<?xml version="1.0"?><rss version="2.0">`, "text/xml", feedMIMEType},
+		{`<?xml version="1.0"?><!-- generated --><?xml-stylesheet href="s.xsl"?><rss>`, "text/xml", feedMIMEType},
+		{"\ufeff<rss version=\"2.0\">", "", feedMIMEType},
+		{`anything`, "application/rss+xml", feedMIMEType},
+		{`<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom">`, "text/xml", atomMIMEType},
+		{`anything`, "application/atom+xml", atomMIMEType},
+		{`{"version": "https://jsonfeed.org/version/1.1"}`, "application/json", jsonFeedMIMEType},
+		{`{"error": "not found"}`, "application/json", ""},
+		{`<!DOCTYPE html><html>`, "text/html", ""},
+		{`<html><body>feed me</body></html>`, "text/html", ""},
+	}
+	for _, tt := range tests {
+		if got := sniffFeedType([]byte(tt.body), tt.contentType); got != tt.want {
+			t.Errorf("sniffFeedType(%q, %q) = %q, want %q", tt.body, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+// newFeedSite serves a page announcing the given <link> tags at /, and
+// files under the paths in feeds with their content types.
+func newFeedSite(t *testing.T, links string, feeds map[string][2]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head>" + links + "</head><body>Blog</body></html>"))
+			return
+		}
+		feed, ok := feeds[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", feed[0])
+		w.Write([]byte(feed[1]))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFindFeedOtherFormats(t *testing.T) {
+	const (
+		atomDoc = `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title></feed>`
+		jsonDoc = `{"version": "https://jsonfeed.org/version/1.1", "title": "Blog"}`
+		rssDoc  = `<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title></channel></rss>`
+	)
+	client := newTestHTTPClient(t, config.HTTPConfig{})
+	ctx := context.Background()
+
+	// Only Atom and JSON: announced, and at the well-known /atom.xml.
+	srv := newFeedSite(t, `<link rel="alternate" type="application/feed+json" href="/feed.json">`,
+		map[string][2]string{
+			"/feed.json": {"application/feed+json", jsonDoc},
+			"/atom.xml":  {"application/atom+xml", atomDoc},
+		})
+	_, _, err := findFeed(ctx, client, srv.URL+"/", 0, nil)
+	if err == nil {
+		t.Fatal("found a feed on a site offering only Atom and JSON Feed")
+	}
+	for _, want := range []string{srv.URL + "/feed.json (JSON Feed)", srv.URL + "/atom.xml (Atom)", "only RSS 2.0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+
+	// An announced Atom feed doesn't stop the well-known RSS paths being tried.
+	srv = newFeedSite(t, `<link rel="alternate" type="application/atom+xml" href="/atom.xml">`,
+		map[string][2]string{
+			"/atom.xml": {"application/atom+xml", atomDoc},
+			"/rss.xml":  {"text/xml", rssDoc},
+		})
+	got, feed, err := findFeed(ctx, client, srv.URL+"/", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != srv.URL+"/rss.xml" || feed.Channel.Title != "Blog" {
+		t.Errorf("findFeed = %q (%q), want %q", got, feed.Channel.Title, srv.URL+"/rss.xml")
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"gator/internal/database"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const defaultExportLimit = 50
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string    `xml:"id"`
+	Title     string    `xml:"title"`
+	Link      atomLink  `xml:"link"`
+	Published string    `xml:"published,omitempty"`
+	Updated   string    `xml:"updated"`
+	Summary   *atomText `xml:"summary,omitempty"`
+	Content   *atomText `xml:"content,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type rssDocument struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		Title         string          `xml:"title"`
+		Link          string          `xml:"link"`
+		Description   string          `xml:"description"`
+		LastBuildDate string          `xml:"lastBuildDate"`
+		Item          []rssOutputItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssOutputItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description,omitempty"`
+	GUID        struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	} `xml:"guid"`
+	PubDate string `xml:"pubDate,omitempty"`
+}
+
+// postTime is the best timestamp we have for a post: when it was published,
+// or failing that when gator first saw it.
+func postTime(post database.Post) time.Time {
+	if post.PublishedAt.Valid {
+		return post.PublishedAt.Time.UTC()
+	}
+	return post.CreatedAt.UTC()
+}
+
+func timelineUpdated(posts []database.Post) time.Time {
+	var latest time.Time
+	for _, post := range posts {
+		if t := postTime(post); t.After(latest) {
+			latest = t
+		}
+	}
+	if latest.IsZero() {
+		latest = time.Now().UTC()
+	}
+	return latest
+}
+
+// renderAtom writes the user's timeline as an Atom 1.0 document. selfURL is
+// optional and, when given, is advertised as the feed's rel="self" link.
+func renderAtom(w io.Writer, user database.User, posts []database.Post, selfURL string) error {
+	feed := atomFeed{
+		ID:      "urn:uuid:" + user.ID.String(),
+		Title:   fmt.Sprintf("gator timeline for %s", user.Name),
+		Updated: timelineUpdated(posts).Format(time.RFC3339),
+		Author:  atomPerson{Name: user.Name},
+	}
+	if selfURL != "" {
+		feed.Links = append(feed.Links, atomLink{Rel: "self", Type: "application/atom+xml", Href: selfURL})
+	}
+	for _, post := range posts {
+		entry := atomEntry{
+			ID:      "urn:uuid:" + post.ID.String(),
+			Title:   post.Title,
+			Link:    atomLink{Rel: "alternate", Href: post.Url},
+			Updated: postTime(post).Format(time.RFC3339),
+		}
+		if post.PublishedAt.Valid {
+			entry.Published = post.PublishedAt.Time.UTC().Format(time.RFC3339)
+		}
+		if post.Description.Valid {
+			entry.Summary = &atomText{Type: "html", Body: post.Description.String}
+		}
+		if post.Content.Valid {
+			entry.Content = &atomText{Type: "html", Body: post.Content.String}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return writeXML(w, feed)
+}
+
+// renderRSS writes the user's timeline as an RSS 2.0 document.
+func renderRSS(w io.Writer, user database.User, posts []database.Post, selfURL string) error {
+	var doc rssDocument
+	doc.Version = "2.0"
+	doc.Channel.Title = fmt.Sprintf("gator timeline for %s", user.Name)
+	doc.Channel.Link = selfURL
+	doc.Channel.Description = fmt.Sprintf("Posts from the feeds %s follows", user.Name)
+	doc.Channel.LastBuildDate = timelineUpdated(posts).Format(time.RFC1123Z)
+	for _, post := range posts {
+		item := rssOutputItem{
+			Title:       post.Title,
+			Link:        post.Url,
+			Description: postHTML(post.Description, post.Content),
+		}
+		item.GUID.Value = post.ID.String()
+		if post.PublishedAt.Valid {
+			item.PubDate = post.PublishedAt.Time.UTC().Format(time.RFC1123Z)
+		}
+		doc.Channel.Item = append(doc.Channel.Item, item)
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func getTimeline(ctx context.Context, s *state, user database.User, limit int) ([]database.Post, error) {
+	return s.db.GetPostsForUserPaginated(ctx, database.GetPostsForUserPaginatedParams{
+		UserID: user.ID,
+		Limit:  int32(limit),
+		Sort:   "time",
+		Offset: 0,
+	})
+}
+
+func exportFeedFlags(fs *flag.FlagSet) {
+	fs.String("format", "atom", "output format: atom | rss")
+	fs.Int("limit", defaultExportLimit, "max posts to include")
+}
+
+func handlerExportFeed(s *state, cmd command, user database.User) error {
+	format, limit := cmd.stringFlag("format"), cmd.intFlag("limit")
+	if format != "atom" && format != "rss" {
+		return usageError("invalid --format value: %q (use \"atom\" or \"rss\")", format)
+	}
+
+	ctx := context.Background()
+	posts, err := getTimeline(ctx, s, user, limit)
+	if err != nil {
+		return fmt.Errorf("failed to get posts: %w", err)
+	}
+	if format == "rss" {
+		return renderRSS(os.Stdout, user, posts, "")
+	}
+	return renderAtom(os.Stdout, user, posts, "")
+}
+
+// apiHandlerUserFeed serves /users/{name}/feed.atom and feed.rss. A token only
+// grants access to its own user's timeline.
+func apiHandlerUserFeed(s *state, format string) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		if r.PathValue("name") != user.Name {
+			respondWithError(w, http.StatusForbidden, "token does not belong to this user")
+			return
+		}
+		posts, err := getTimeline(r.Context(), s, user, defaultExportLimit)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "unable to load posts")
+			return
+		}
+		selfURL := requestURL(r)
+		if format == "rss" {
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+			renderRSS(w, user, posts, selfURL)
+			return
+		}
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		renderAtom(w, user, posts, selfURL)
+	}
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The Fever API (https://feedafever.com/api) is a single endpoint driven by
+// query-string verbs: POST /fever/?api&items&since_id=42. Clients compute
+// api_key as md5("<email>:<password>"); gator users enter their user name
+// and an API token from `gator token create`.
+
+const feverItemLimit = 50
+
+// feverAllGroup is the only group gator reports; it contains every feed.
+const feverAllGroup = 1
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	SiteURL           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	URL           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+func feverUser(ctx context.Context, s *state, apiKey string) (database.User, error) {
+	if apiKey == "" {
+		return database.User{}, sql.ErrNoRows
+	}
+	row, err := s.db.GetUserByFeverKey(ctx, sql.NullString{String: hashFeverKey(apiKey), Valid: true})
+	if err != nil {
+		return database.User{}, err
+	}
+	return checkAPIToken(ctx, s, database.GetUserByAPITokenRow(row), tokenKindAPI)
+}
+
+func apiHandlerFever(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid form")
+			return
+		}
+		resp := map[string]any{"api_version": 3, "auth": 0}
+
+		ctx := r.Context()
+		user, err := feverUser(ctx, s, r.PostFormValue("api_key"))
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, errInvalidToken) && !errors.Is(err, errTokenExpired) {
+				log.Printf("Failed to look up fever key: %+v", err)
+			}
+			// Fever reports bad credentials in-band, not with a status code.
+			respondWithJSON(w, http.StatusOK, resp)
+			return
+		}
+		resp["auth"] = 1
+		resp["last_refreshed_on_time"] = time.Now().Unix()
+
+		query := r.URL.Query()
+		if err := feverMark(ctx, s, user, r); err != nil {
+			log.Printf("Failed to mark fever items: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "unable to mark items")
+			return
+		}
+		if query.Has("groups") || query.Has("feeds") {
+			feeds, err := s.db.GetFeedsForUser(ctx, user.ID)
+			if err != nil {
+				log.Printf("Failed to get feeds: %+v", err)
+				respondWithError(w, http.StatusInternalServerError, "unable to load feeds")
+				return
+			}
+			ids := make([]string, 0, len(feeds))
+			for _, feed := range feeds {
+				ids = append(ids, strconv.FormatInt(feed.Seq, 10))
+			}
+			resp["feeds_groups"] = []feverFeedsGroup{{GroupID: feverAllGroup, FeedIDs: strings.Join(ids, ",")}}
+			if query.Has("groups") {
+				resp["groups"] = []map[string]any{{"id": feverAllGroup, "title": "All"}}
+			}
+			if query.Has("feeds") {
+				out := make([]feverFeed, 0, len(feeds))
+				for _, feed := range feeds {
+					out = append(out, feverFeed{
+						ID:                feed.Seq,
+						Title:             feed.Name,
+						URL:               feed.Url,
+						SiteURL:           feed.Url,
+						LastUpdatedOnTime: unixOrZero(feed.LastFetchedAt),
+					})
+				}
+				resp["feeds"] = out
+			}
+		}
+		if query.Has("favicons") {
+			resp["favicons"] = []any{}
+		}
+		if query.Has("links") {
+			resp["links"] = []any{}
+		}
+		if query.Has("items") {
+			items, total, err := feverItems(ctx, s, user, query.Get("since_id"), query.Get("max_id"), query.Get("with_ids"))
+			if err != nil {
+				log.Printf("Failed to get fever items: %+v", err)
+				respondWithError(w, http.StatusInternalServerError, "unable to load items")
+				return
+			}
+			resp["items"] = items
+			resp["total_items"] = total
+		}
+		if query.Has("unread_item_ids") || r.PostFormValue("as") == "read" || r.PostFormValue("as") == "unread" {
+			ids, err := s.db.GetPostSeqsForUser(ctx, database.GetPostSeqsForUserParams{UserID: user.ID, UnreadOnly: true})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "unable to load items")
+				return
+			}
+			resp["unread_item_ids"] = joinSeqs(ids)
+		}
+		if query.Has("saved_item_ids") || r.PostFormValue("as") == "saved" || r.PostFormValue("as") == "unsaved" {
+			ids, err := s.db.GetPostSeqsForUser(ctx, database.GetPostSeqsForUserParams{UserID: user.ID, StarredOnly: true})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "unable to load items")
+				return
+			}
+			resp["saved_item_ids"] = joinSeqs(ids)
+		}
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func feverItems(ctx context.Context, s *state, user database.User, sinceID, maxID, withIDs string) ([]feverItem, int, error) {
+	all, err := s.db.GetPostSeqsForUser(ctx, database.GetPostSeqsForUserParams{UserID: user.ID})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var rows []database.GetPostsWithStateForUserRow
+	if withIDs != "" {
+		seqs := parseSeqs(withIDs, feverItemLimit)
+		byID, err := s.db.GetPostsWithStateBySeq(ctx, database.GetPostsWithStateBySeqParams{UserID: user.ID, Seqs: seqs})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, row := range byID {
+			rows = append(rows, database.GetPostsWithStateForUserRow(row))
+		}
+	} else {
+		params := database.GetPostsWithStateForUserParams{UserID: user.ID, MaxItems: feverItemLimit}
+		if sinceID != "" {
+			params.SinceSeq, _ = strconv.ParseInt(sinceID, 10, 64)
+			params.OldestFirst = true
+		} else if maxID != "" {
+			params.MaxSeq, _ = strconv.ParseInt(maxID, 10, 64)
+		}
+		rows, err = s.db.GetPostsWithStateForUser(ctx, params)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	items := make([]feverItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, feverItem{
+			ID:            row.Seq,
+			FeedID:        row.FeedSeq,
+			Title:         row.Title,
+			Author:        row.Author.String,
+			HTML:          postHTML(row.Description, row.Content),
+			URL:           row.Url,
+			IsSaved:       boolInt(row.IsStarred),
+			IsRead:        boolInt(row.IsRead),
+			CreatedOnTime: postRowTime(row).Unix(),
+		})
+	}
+	return items, len(all), nil
+}
+
+// feverMark applies a mark=item|feed|group&as=...&id=... request, if any.
+func feverMark(ctx context.Context, s *state, user database.User, r *http.Request) error {
+	mark, as := r.PostFormValue("mark"), r.PostFormValue("as")
+	if mark == "" {
+		return nil
+	}
+	id, err := strconv.ParseInt(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	switch mark {
+	case "item":
+		posts, err := s.db.GetPostsWithStateBySeq(ctx, database.GetPostsWithStateBySeqParams{UserID: user.ID, Seqs: []int64{id}})
+		if err != nil || len(posts) == 0 {
+			return err
+		}
+		return setPostState(ctx, s, user, posts[0].ID, as)
+	case "feed", "group":
+		if as != "read" {
+			return nil
+		}
+		before := time.Now().UTC()
+		if sec, err := strconv.ParseInt(r.PostFormValue("before"), 10, 64); err == nil && sec > 0 {
+			before = time.Unix(sec, 0).UTC()
+		}
+		params := database.MarkPostsReadBeforeParams{UserID: user.ID, Before: before}
+		if mark == "feed" {
+			params.FeedSeq = id
+		}
+		return s.db.MarkPostsReadBefore(ctx, params)
+	}
+	return nil
+}
+
+// setPostState applies one of the Fever "as" values (read, unread, saved,
+// unsaved) to a post.
+func setPostState(ctx context.Context, s *state, user database.User, postID uuid.UUID, as string) error {
+	switch as {
+	case "read":
+		return s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: postID})
+	case "unread":
+		return s.db.MarkPostUnread(ctx, database.MarkPostUnreadParams{UserID: user.ID, PostID: postID})
+	case "saved":
+		return s.db.StarPost(ctx, database.StarPostParams{UserID: user.ID, PostID: postID})
+	case "unsaved":
+		return s.db.UnstarPost(ctx, database.UnstarPostParams{UserID: user.ID, PostID: postID})
+	}
+	return nil
+}
+
+func postRowTime(row database.GetPostsWithStateForUserRow) time.Time {
+	if row.PublishedAt.Valid {
+		return row.PublishedAt.Time.UTC()
+	}
+	return row.CreatedAt.UTC()
+}
+
+func parseSeqs(list string, limit int) []int64 {
+	var seqs []int64
+	for _, field := range strings.Split(list, ",") {
+		seq, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+		if len(seqs) == limit {
+			break
+		}
+	}
+	return seqs
+}
+
+func joinSeqs(seqs []int64) string {
+	parts := make([]string, 0, len(seqs))
+	for _, seq := range seqs {
+		parts = append(parts, strconv.FormatInt(seq, 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func unixOrZero(t sql.NullTime) int64 {
+	if !t.Valid {
+		return 0
+	}
+	return t.Time.Unix()
+}
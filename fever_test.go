@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func feverForm(apiKey string, extra ...string) url.Values {
+	form := url.Values{"api_key": {apiKey}}
+	for i := 0; i+1 < len(extra); i += 2 {
+		form.Set(extra[i], extra[i+1])
+	}
+	return form
+}
+
+// feverItemState checks is_read and is_saved of the items in a response.
+func feverItemState(want map[int64][2]int) func(t *testing.T, body []byte) {
+	return func(t *testing.T, body []byte) {
+		var resp struct {
+			Items []feverItem `json:"items"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if len(resp.Items) != len(want) {
+			t.Fatalf("got %d items, want %d: %s", len(resp.Items), len(want), body)
+		}
+		for _, item := range resp.Items {
+			if got := [2]int{item.IsRead, item.IsSaved}; got != want[item.ID] {
+				t.Errorf("item %d: is_read, is_saved = %v, want %v", item.ID, got, want[item.ID])
+			}
+		}
+	}
+}
+
+func TestFeverReplay(t *testing.T) {
+	db := newSyncFixture()
+	handler := newServeMux(newTestState(db))
+	key := feverKey("alice", testToken)
+	for _, token := range db.tokens {
+		if token.FeverKey.String == key {
+			t.Fatal("fever key stored in the clear")
+		}
+	}
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	replay(t, handler, []replayStep{
+		{name: "wrong api_key", method: "POST", path: "/fever/?api&items",
+			form: feverForm(feverKey("alice", "gator_wrong")),
+			want: []string{`"auth":0`}, notWant: []string{`"items"`}},
+		{name: "missing api_key", method: "POST", path: "/fever/?api",
+			form: url.Values{}, want: []string{`"auth":0`, `"api_version":3`}},
+		{name: "key is case-insensitive", method: "POST", path: "/fever/?api",
+			form: feverForm(strings.ToUpper(key)), want: []string{`"auth":1`}},
+		{name: "groups and feeds", method: "POST", path: "/fever/?api&groups&feeds",
+			form:    feverForm(key),
+			want:    []string{`"feed_ids":"1,2"`, `"title":"Go Blog"`, `"title":"Rust Blog"`},
+			notWant: []string{"Bob's feed"}},
+		{name: "all items", method: "POST", path: "/fever/?api&items",
+			form:    feverForm(key),
+			want:    []string{`"auth":1`, `"total_items":3`, `"id":3`, `"id":2`, `"id":1`},
+			notWant: []string{`"id":4`, "Bob only"}},
+		{name: "since_id", method: "POST", path: "/fever/?api&items&since_id=1",
+			form: feverForm(key),
+			want: []string{`"id":2`, `"id":3`}, notWant: []string{`"id":1,`}},
+		{name: "max_id", method: "POST", path: "/fever/?api&items&max_id=3",
+			form: feverForm(key),
+			want: []string{`"id":2`, `"id":1`}, notWant: []string{`"id":3`}},
+		{name: "with_ids skips other users' items", method: "POST", path: "/fever/?api&items&with_ids=1,4",
+			form: feverForm(key),
+			want: []string{`"id":1`}, notWant: []string{`"id":4`}},
+		{name: "mark item read", method: "POST", path: "/fever/?api",
+			form: feverForm(key, "mark", "item", "as", "read", "id", "2"),
+			want: []string{`"unread_item_ids":"3,1"`}},
+		{name: "mark item saved", method: "POST", path: "/fever/?api",
+			form: feverForm(key, "mark", "item", "as", "saved", "id", "1"),
+			want: []string{`"saved_item_ids":"1"`}},
+		{name: "items carry state", method: "POST", path: "/fever/?api&items&with_ids=1,2,3",
+			form:  feverForm(key),
+			check: feverItemState(map[int64][2]int{1: {0, 1}, 2: {1, 0}, 3: {0, 0}})},
+		{name: "mark item unread", method: "POST", path: "/fever/?api",
+			form: feverForm(key, "mark", "item", "as", "unread", "id", "2"),
+			want: []string{`"unread_item_ids":"3,2,1"`}},
+		{name: "mark feed read", method: "POST", path: "/fever/?api",
+			form: feverForm(key, "mark", "feed", "as", "read", "id", "1", "before", future),
+			want: []string{`"unread_item_ids":"3"`}},
+		{name: "mark other user's item is a no-op", method: "POST", path: "/fever/?api",
+			form: feverForm(key, "mark", "item", "as", "read", "id", "4"),
+			want: []string{`"unread_item_ids":"3"`}},
+		{name: "mark group read", method: "POST", path: "/fever/?api",
+			form: feverForm(key, "mark", "group", "as", "read", "id", "1", "before", future),
+			want: []string{`"unread_item_ids":""`}},
+		{name: "unsave", method: "POST", path: "/fever/?api&saved_item_ids",
+			form: feverForm(key, "mark", "item", "as", "unsaved", "id", "1"),
+			want: []string{`"saved_item_ids":""`}},
+	})
+
+	if !db.tokens[0].LastUsedAt.Valid {
+		t.Error("token use was not recorded")
+	}
+}
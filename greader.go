@@ -0,0 +1,571 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A subset of the Google Reader API as implemented by FreshRSS and Miniflux,
+// which is what Reeder, ReadKit and NetNewsWire speak. Clients log in through
+// ClientLogin with the gator user name and an API token as the password, then
+// send "Authorization: GoogleLogin auth=<token>" on every request.
+
+const (
+	readerItemPrefix   = "tag:google.com,2005:reader/item/"
+	readerReadingList  = "user/-/state/com.google/reading-list"
+	readerRead         = "user/-/state/com.google/read"
+	readerStarred      = "user/-/state/com.google/starred"
+	readerKeptUnread   = "user/-/state/com.google/kept-unread"
+	readerFeedPrefix   = "feed/"
+	readerDefaultItems = 20
+	readerMaxItems     = 10000
+)
+
+type readerSubscription struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Categories []string `json:"categories"`
+	URL        string   `json:"url"`
+	HTMLURL    string   `json:"htmlUrl"`
+	IconURL    string   `json:"iconUrl"`
+}
+
+type readerLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type readerItem struct {
+	ID            string       `json:"id"`
+	CrawlTimeMsec string       `json:"crawlTimeMsec"`
+	TimestampUsec string       `json:"timestampUsec"`
+	Published     int64        `json:"published"`
+	Updated       int64        `json:"updated"`
+	Title         string       `json:"title"`
+	Canonical     []readerLink `json:"canonical"`
+	Alternate     []readerLink `json:"alternate"`
+	Summary       struct {
+		Direction string `json:"direction"`
+		Content   string `json:"content"`
+	} `json:"summary"`
+	Categories []string `json:"categories"`
+	Origin     struct {
+		StreamID string `json:"streamId"`
+		Title    string `json:"title"`
+		HTMLURL  string `json:"htmlUrl"`
+	} `json:"origin"`
+	Author string `json:"author"`
+}
+
+// readerStream is a parsed stream ID. feedSeq is set for feed/<seq> streams.
+type readerStream struct {
+	state   string
+	feedSeq int64
+}
+
+func parseReaderStream(id string) (readerStream, error) {
+	if rest, ok := strings.CutPrefix(id, readerFeedPrefix); ok {
+		seq, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			return readerStream{}, fmt.Errorf("unknown feed stream %q", id)
+		}
+		return readerStream{feedSeq: seq}, nil
+	}
+	// Clients may use either user/-/... or user/<id>/...
+	if i := strings.Index(id, "/state/com.google/"); strings.HasPrefix(id, "user/") && i >= 0 {
+		return readerStream{state: "user/-" + id[i:]}, nil
+	}
+	return readerStream{}, fmt.Errorf("unsupported stream %q", id)
+}
+
+// parseReaderItemID accepts both the long "tag:google.com,..." form, which is
+// hex, and the short decimal form.
+func parseReaderItemID(id string) (int64, error) {
+	if hexID, ok := strings.CutPrefix(id, readerItemPrefix); ok {
+		u, err := strconv.ParseUint(hexID, 16, 64)
+		return int64(u), err
+	}
+	return strconv.ParseInt(id, 10, 64)
+}
+
+func readerItemID(seq int64) string {
+	return fmt.Sprintf("%s%016x", readerItemPrefix, seq)
+}
+
+func readerFeedStream(seq int64) string {
+	return readerFeedPrefix + strconv.FormatInt(seq, 10)
+}
+
+func respondWithText(w http.ResponseWriter, code int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write([]byte(body))
+}
+
+func apiHandlerReaderLogin(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, token := r.FormValue("Email"), r.FormValue("Passwd")
+		row, err := s.db.GetUserByAPIToken(r.Context(), hashAPIToken(token))
+		if err != nil || !strings.EqualFold(row.Name, name) {
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("Failed to look up token: %+v", err)
+			}
+			respondWithText(w, http.StatusUnauthorized, "Error=BadAuthentication\n")
+			return
+		}
+		if _, err := checkAPIToken(r.Context(), s, row, tokenKindAPI); err != nil {
+			respondWithText(w, http.StatusUnauthorized, "Error=BadAuthentication\n")
+			return
+		}
+		respondWithText(w, http.StatusOK, fmt.Sprintf("SID=%s\nLSID=%s\nAuth=%s\n", token, token, token))
+	}
+}
+
+func apiHandlerReaderToken(w http.ResponseWriter, r *http.Request, user database.User) {
+	// Write requests are authenticated by the Authorization header, so the
+	// action token only has to be stable and non-empty.
+	respondWithText(w, http.StatusOK, strings.ReplaceAll(user.ID.String(), "-", ""))
+}
+
+func apiHandlerReaderUserInfo(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     user.Name,
+	})
+}
+
+func apiHandlerReaderTags(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"tags": []map[string]string{{"id": readerStarred}},
+	})
+}
+
+func apiHandlerReaderSubscriptions(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		feeds, err := s.db.GetFeedsForUser(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("Failed to get feeds: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "unable to load subscriptions")
+			return
+		}
+		subs := make([]readerSubscription, 0, len(feeds))
+		for _, feed := range feeds {
+			subs = append(subs, readerSubscription{
+				ID:         readerFeedStream(feed.Seq),
+				Title:      feed.Name,
+				Categories: []string{},
+				URL:        feed.Url,
+				HTMLURL:    feed.Url,
+			})
+		}
+		respondWithJSON(w, http.StatusOK, map[string]any{"subscriptions": subs})
+	}
+}
+
+// apiHandlerReaderEditSubscription handles ac=subscribe|unsubscribe|edit.
+// New subscriptions are given as feed/<url>; existing ones as feed/<seq>.
+func apiHandlerReaderEditSubscription(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		ctx := r.Context()
+		streamID := r.FormValue("s")
+		switch r.FormValue("ac") {
+		case "subscribe":
+			feedURL := strings.TrimPrefix(streamID, readerFeedPrefix)
+			if _, err := followFeedURL(ctx, s, user, feedURL, r.FormValue("t")); err != nil {
+				respondWithText(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		case "unsubscribe":
+			feed, err := readerFeed(ctx, s, streamID)
+			if err != nil {
+				respondWithText(w, http.StatusNotFound, err.Error())
+				return
+			}
+			err = s.db.UnFollow(ctx, database.UnFollowParams{UserID: user.ID, FeedID: feed.ID})
+			if err != nil {
+				respondWithText(w, http.StatusInternalServerError, "unable to unsubscribe")
+				return
+			}
+		case "edit":
+			// Renaming and labels are not supported; accept and ignore.
+		default:
+			respondWithText(w, http.StatusBadRequest, "unknown action")
+			return
+		}
+		respondWithText(w, http.StatusOK, "OK")
+	}
+}
+
+func apiHandlerReaderQuickAdd(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		feedURL := strings.TrimPrefix(r.FormValue("quickadd"), readerFeedPrefix)
+		feed, err := followFeedURL(r.Context(), s, user, feedURL, "")
+		if err != nil {
+			respondWithJSON(w, http.StatusOK, map[string]any{"numResults": 0, "error": err.Error()})
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]any{
+			"numResults": 1,
+			"query":      feed.Url,
+			"streamId":   readerFeedStream(feed.Seq),
+			"streamName": feed.Name,
+		})
+	}
+}
+
+func apiHandlerReaderUnreadCount(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		counts, err := s.db.GetUnreadCountsForUser(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("Failed to get unread counts: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "unable to count unread items")
+			return
+		}
+		var total int64
+		var newest time.Time
+		out := make([]map[string]any, 0, len(counts)+1)
+		for _, c := range counts {
+			total += c.Unread
+			if c.Newest.After(newest) {
+				newest = c.Newest
+			}
+			out = append(out, map[string]any{
+				"id":                      readerFeedStream(c.FeedSeq),
+				"count":                   c.Unread,
+				"newestItemTimestampUsec": strconv.FormatInt(c.Newest.UnixMicro(), 10),
+			})
+		}
+		out = append(out, map[string]any{
+			"id":                      readerReadingList,
+			"count":                   total,
+			"newestItemTimestampUsec": strconv.FormatInt(newest.UnixMicro(), 10),
+		})
+		respondWithJSON(w, http.StatusOK, map[string]any{"max": readerMaxItems, "unreadcounts": out})
+	}
+}
+
+// readerStreamQuery turns the stream parameters shared by stream/items/ids and
+// stream/contents (n, xt, it, r, c, nt) into a posts query.
+func readerStreamQuery(r *http.Request, user database.User, streamID string) (database.GetPostsWithStateForUserParams, error) {
+	params := database.GetPostsWithStateForUserParams{UserID: user.ID, MaxItems: readerDefaultItems}
+	stream, err := parseReaderStream(streamID)
+	if err != nil {
+		return params, err
+	}
+	switch stream.state {
+	case "", readerReadingList:
+	case readerStarred:
+		params.StarredOnly = true
+	default:
+		return params, fmt.Errorf("unsupported stream %q", streamID)
+	}
+	params.FeedSeq = stream.feedSeq
+
+	if n, err := strconv.Atoi(r.FormValue("n")); err == nil && n > 0 {
+		params.MaxItems = int32(min(n, readerMaxItems))
+	}
+	for _, exclude := range r.Form["xt"] {
+		if exclude == readerRead {
+			params.UnreadOnly = true
+		}
+	}
+	for _, include := range r.Form["it"] {
+		if include == readerStarred {
+			params.StarredOnly = true
+		}
+	}
+	if sec, err := strconv.ParseInt(r.FormValue("nt"), 10, 64); err == nil && sec > 0 {
+		params.NewerThan = sql.NullTime{Time: time.Unix(sec, 0).UTC(), Valid: true}
+	}
+	params.OldestFirst = r.FormValue("r") == "o"
+	if c, err := strconv.ParseInt(r.FormValue("c"), 10, 64); err == nil {
+		if params.OldestFirst {
+			params.SinceSeq = c
+		} else {
+			params.MaxSeq = c
+		}
+	}
+	return params, nil
+}
+
+// readerContinuation is the c= value for the page after rows, or "".
+func readerContinuation(rows []database.GetPostsWithStateForUserRow, params database.GetPostsWithStateForUserParams) string {
+	if len(rows) < int(params.MaxItems) || len(rows) == 0 {
+		return ""
+	}
+	return strconv.FormatInt(rows[len(rows)-1].Seq, 10)
+}
+
+func apiHandlerReaderItemIDs(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		r.ParseForm()
+		params, err := readerStreamQuery(r, user, r.FormValue("s"))
+		if err != nil {
+			respondWithText(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		rows, err := s.db.GetPostsWithStateForUser(r.Context(), params)
+		if err != nil {
+			log.Printf("Failed to get posts: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "unable to load items")
+			return
+		}
+		refs := make([]map[string]string, 0, len(rows))
+		for _, row := range rows {
+			refs = append(refs, map[string]string{"id": strconv.FormatInt(row.Seq, 10)})
+		}
+		resp := map[string]any{"itemRefs": refs}
+		if c := readerContinuation(rows, params); c != "" {
+			resp["continuation"] = c
+		}
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func apiHandlerReaderStreamContents(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		r.ParseForm()
+		streamID := r.PathValue("stream")
+		if streamID == "" {
+			streamID = r.FormValue("s")
+		}
+		params, err := readerStreamQuery(r, user, streamID)
+		if err != nil {
+			respondWithText(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		rows, err := s.db.GetPostsWithStateForUser(r.Context(), params)
+		if err != nil {
+			log.Printf("Failed to get posts: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "unable to load items")
+			return
+		}
+		resp := map[string]any{
+			"direction": "ltr",
+			"id":        streamID,
+			"updated":   time.Now().Unix(),
+			"items":     readerItems(rows),
+		}
+		if c := readerContinuation(rows, params); c != "" {
+			resp["continuation"] = c
+		}
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func apiHandlerReaderItemContents(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		r.ParseForm()
+		var seqs []int64
+		for _, id := range r.Form["i"] {
+			seq, err := parseReaderItemID(id)
+			if err != nil {
+				respondWithText(w, http.StatusBadRequest, fmt.Sprintf("invalid item id %q", id))
+				return
+			}
+			seqs = append(seqs, seq)
+		}
+		byID, err := s.db.GetPostsWithStateBySeq(r.Context(), database.GetPostsWithStateBySeqParams{UserID: user.ID, Seqs: seqs})
+		if err != nil {
+			log.Printf("Failed to get posts: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "unable to load items")
+			return
+		}
+		rows := make([]database.GetPostsWithStateForUserRow, 0, len(byID))
+		for _, row := range byID {
+			rows = append(rows, database.GetPostsWithStateForUserRow(row))
+		}
+		respondWithJSON(w, http.StatusOK, map[string]any{
+			"direction": "ltr",
+			"id":        readerReadingList,
+			"updated":   time.Now().Unix(),
+			"items":     readerItems(rows),
+		})
+	}
+}
+
+func readerItems(rows []database.GetPostsWithStateForUserRow) []readerItem {
+	items := make([]readerItem, 0, len(rows))
+	for _, row := range rows {
+		published := postRowTime(row)
+		item := readerItem{
+			ID:            readerItemID(row.Seq),
+			CrawlTimeMsec: strconv.FormatInt(row.CreatedAt.UnixMilli(), 10),
+			TimestampUsec: strconv.FormatInt(published.UnixMicro(), 10),
+			Published:     published.Unix(),
+			Updated:       published.Unix(),
+			Title:         row.Title,
+			Canonical:     []readerLink{{Href: row.Url}},
+			Alternate:     []readerLink{{Href: row.Url, Type: "text/html"}},
+			Categories:    []string{readerReadingList},
+		}
+		item.Summary.Direction = "ltr"
+		item.Summary.Content = postHTML(row.Description, row.Content)
+		item.Author = row.Author.String
+		item.Origin.StreamID = readerFeedStream(row.FeedSeq)
+		item.Origin.Title = row.FeedName
+		item.Origin.HTMLURL = row.FeedUrl
+		if row.IsRead {
+			item.Categories = append(item.Categories, readerRead)
+		}
+		if row.IsStarred {
+			item.Categories = append(item.Categories, readerStarred)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// apiHandlerReaderEditTag adds (a=) and removes (r=) the read and starred
+// states on the items given as i=.
+func apiHandlerReaderEditTag(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		r.ParseForm()
+		ctx := r.Context()
+		var seqs []int64
+		for _, id := range r.Form["i"] {
+			seq, err := parseReaderItemID(id)
+			if err != nil {
+				respondWithText(w, http.StatusBadRequest, fmt.Sprintf("invalid item id %q", id))
+				return
+			}
+			seqs = append(seqs, seq)
+		}
+		posts, err := s.db.GetPostsWithStateBySeq(ctx, database.GetPostsWithStateBySeqParams{UserID: user.ID, Seqs: seqs})
+		if err != nil {
+			log.Printf("Failed to get posts: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "unable to load items")
+			return
+		}
+
+		var changes []string
+		for _, tag := range r.Form["a"] {
+			switch tag {
+			case readerRead:
+				changes = append(changes, "read")
+			case readerStarred:
+				changes = append(changes, "saved")
+			case readerKeptUnread:
+				changes = append(changes, "unread")
+			}
+		}
+		for _, tag := range r.Form["r"] {
+			switch tag {
+			case readerRead:
+				changes = append(changes, "unread")
+			case readerStarred:
+				changes = append(changes, "unsaved")
+			}
+		}
+		for _, post := range posts {
+			for _, change := range changes {
+				if err := setPostState(ctx, s, user, post.ID, change); err != nil {
+					log.Printf("Failed to update post state: %+v", err)
+					respondWithError(w, http.StatusInternalServerError, "unable to update items")
+					return
+				}
+			}
+		}
+		respondWithText(w, http.StatusOK, "OK")
+	}
+}
+
+func apiHandlerReaderMarkAllRead(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		stream, err := parseReaderStream(r.FormValue("s"))
+		if err != nil {
+			respondWithText(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Only a feed or everything can be marked; "all starred" would
+		// otherwise mark every post read.
+		if stream.feedSeq == 0 && stream.state != readerReadingList {
+			respondWithText(w, http.StatusBadRequest, fmt.Sprintf("cannot mark stream %q as read", r.FormValue("s")))
+			return
+		}
+		before := time.Now().UTC()
+		if usec, err := strconv.ParseInt(r.FormValue("ts"), 10, 64); err == nil && usec > 0 {
+			before = time.UnixMicro(usec).UTC()
+		}
+		err = s.db.MarkPostsReadBefore(r.Context(), database.MarkPostsReadBeforeParams{
+			UserID:  user.ID,
+			Before:  before,
+			FeedSeq: stream.feedSeq,
+		})
+		if err != nil {
+			log.Printf("Failed to mark posts read: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "unable to mark items read")
+			return
+		}
+		respondWithText(w, http.StatusOK, "OK")
+	}
+}
+
+func readerFeed(ctx context.Context, s *state, streamID string) (database.Feed, error) {
+	stream, err := parseReaderStream(streamID)
+	if err != nil || stream.feedSeq == 0 {
+		return database.Feed{}, fmt.Errorf("unknown feed %q", streamID)
+	}
+	return s.db.GetFeedBySeq(ctx, stream.feedSeq)
+}
+
+// followFeedURL follows the feed at feedURL, adding it first (like addfeed)
+// if nobody has added it yet. As with addfeed, a web page is searched for
+// its feed and a permanently moved feed is added under its new URL. title
+// defaults to the feed's own title.
+func followFeedURL(ctx context.Context, s *state, user database.User, feedURL, title string) (database.Feed, error) {
+	feed, err := s.db.GetFeedByURL(ctx, feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		feed, err = addFeedURL(ctx, s, user, feedURL, title)
+	}
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	_, err = s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+		err = nil
+	}
+	return feed, err
+}
+
+// addFeedURL finds the feed at pageURL and adds it, unless it turns out to
+// be one that is already stored under its discovered or moved-to URL.
+func addFeedURL(ctx context.Context, s *state, user database.User, pageURL, title string) (database.Feed, error) {
+	feedURL, rss, err := findFeed(ctx, s.http, pageURL, 0, nil)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	if feed, err := s.db.GetFeedByURL(ctx, feedURL); !errors.Is(err, sql.ErrNoRows) {
+		return feed, err
+	}
+	if title == "" {
+		title = strings.TrimSpace(rss.Channel.Title)
+	}
+	if title == "" {
+		title = feedURL
+	}
+	link, description := channelMetadata(rss)
+	return s.db.AddFeed(ctx, database.AddFeedParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Name:        title,
+		Url:         feedURL,
+		UserID:      user.ID,
+		Link:        link,
+		Description: description,
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"gator/internal/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestReaderReplay(t *testing.T) {
+	handler := newServeMux(newTestState(newSyncFixture()))
+	const (
+		contents = "/reader/api/0/stream/contents/"
+		item1    = readerItemPrefix + "0000000000000001"
+		item2    = readerItemPrefix + "0000000000000002"
+		item3    = readerItemPrefix + "0000000000000003"
+		item4    = readerItemPrefix + "0000000000000004"
+	)
+
+	replay(t, handler, []replayStep{
+		{name: "ClientLogin with a wrong token", method: "POST", path: "/accounts/ClientLogin",
+			form:       url.Values{"Email": {"alice"}, "Passwd": {"gator_wrong"}},
+			wantStatus: http.StatusUnauthorized, want: []string{"Error=BadAuthentication"}},
+		{name: "ClientLogin with another user's name", method: "POST", path: "/accounts/ClientLogin",
+			form:       url.Values{"Email": {"bob"}, "Passwd": {testToken}},
+			wantStatus: http.StatusUnauthorized, want: []string{"Error=BadAuthentication"}},
+		{name: "ClientLogin with a web session token", method: "POST", path: "/accounts/ClientLogin",
+			form:       url.Values{"Email": {"alice"}, "Passwd": {testSession}},
+			wantStatus: http.StatusUnauthorized, want: []string{"Error=BadAuthentication"}},
+		{name: "ClientLogin", method: "POST", path: "/accounts/ClientLogin",
+			form: url.Values{"Email": {"Alice"}, "Passwd": {testToken}},
+			want: []string{"SID=" + testToken, "Auth=" + testToken}},
+		{name: "unauthenticated", method: "GET", path: contents + readerReadingList,
+			wantStatus: http.StatusUnauthorized},
+
+		{name: "reading list", method: "GET", path: contents + readerReadingList, auth: true,
+			want:    []string{item3, item2, item1, `"title":"Rust 1.80"`, `"streamId":"feed/2"`},
+			notWant: []string{item4, "Bob only"}},
+		{name: "feed stream", method: "GET", path: contents + "feed/1", auth: true,
+			want: []string{item2, item1}, notWant: []string{item3}},
+		{name: "first page", method: "GET", path: contents + readerReadingList + "?n=2", auth: true,
+			want: []string{item3, item2, `"continuation":"2"`}, notWant: []string{item1}},
+		{name: "next page", method: "GET", path: contents + readerReadingList + "?n=2&c=2", auth: true,
+			want: []string{item1}, notWant: []string{item2, `"continuation"`}},
+		{name: "oldest first", method: "GET", path: contents + readerReadingList + "?r=o&n=1", auth: true,
+			want: []string{item1, `"continuation":"1"`}, notWant: []string{item2}},
+		{name: "unknown stream", method: "GET", path: contents + "user/-/label/nope", auth: true,
+			wantStatus: http.StatusBadRequest},
+
+		{name: "mark read by long id", method: "POST", path: "/reader/api/0/edit-tag", auth: true,
+			form: url.Values{"i": {item2}, "a": {readerRead}}, want: []string{"OK"}},
+		{name: "unread only", method: "GET", path: contents + readerReadingList + "?xt=" + readerRead, auth: true,
+			want: []string{item3, item1}, notWant: []string{item2}},
+		{name: "read item is tagged", method: "GET", path: contents + "feed/1", auth: true,
+			want: []string{`"categories":["` + readerReadingList + `","` + readerRead + `"]`}},
+		{name: "star by short id", method: "POST", path: "/reader/api/0/edit-tag", auth: true,
+			form: url.Values{"i": {"1", "3"}, "a": {readerStarred}}, want: []string{"OK"}},
+		{name: "starred stream", method: "GET", path: contents + readerStarred, auth: true,
+			want: []string{item3, item1}, notWant: []string{item2}},
+		{name: "unstar and mark unread", method: "POST", path: "/reader/api/0/edit-tag", auth: true,
+			form: url.Values{"i": {"3", "2"}, "r": {readerStarred, readerRead}}, want: []string{"OK"}},
+		{name: "state after removing tags", method: "GET", path: contents + readerStarred, auth: true,
+			want: []string{item1}, notWant: []string{item2, item3}},
+		{name: "all unread again", method: "GET", path: contents + readerReadingList + "?xt=" + readerRead, auth: true,
+			want: []string{item3, item2, item1}},
+		{name: "kept-unread", method: "POST", path: "/reader/api/0/edit-tag", auth: true,
+			form: url.Values{"i": {"1"}, "a": {readerRead, readerKeptUnread}}, want: []string{"OK"}},
+		{name: "kept-unread wins", method: "GET", path: contents + readerReadingList + "?xt=" + readerRead, auth: true,
+			want: []string{item1}},
+		{name: "other user's item is untouched", method: "POST", path: "/reader/api/0/edit-tag", auth: true,
+			form: url.Values{"i": {"4"}, "a": {readerRead}}, want: []string{"OK"}},
+		{name: "invalid item id", method: "POST", path: "/reader/api/0/edit-tag", auth: true,
+			form: url.Values{"i": {"zz"}, "a": {readerRead}}, wantStatus: http.StatusBadRequest},
+		{name: "edit-tag is POST only", method: "GET", path: "/reader/api/0/edit-tag?i=1&a=" + readerRead, auth: true,
+			wantStatus: http.StatusMethodNotAllowed},
+		{name: "mark all starred read", method: "POST", path: "/reader/api/0/mark-all-as-read", auth: true,
+			form: url.Values{"s": {readerStarred}}, wantStatus: http.StatusBadRequest},
+		{name: "nothing marked by the refused request", method: "GET", path: contents + readerReadingList + "?xt=" + readerRead, auth: true,
+			want: []string{item1, item2, item3}},
+		{name: "mark feed read", method: "POST", path: "/reader/api/0/mark-all-as-read", auth: true,
+			form: url.Values{"s": {"feed/1"}}, want: []string{"OK"}},
+		{name: "only that feed is read", method: "GET", path: contents + readerReadingList + "?xt=" + readerRead, auth: true,
+			want: []string{item3}, notWant: []string{item1, item2}},
+		{name: "mark everything read", method: "POST", path: "/reader/api/0/mark-all-as-read", auth: true,
+			form: url.Values{"s": {readerReadingList}}, want: []string{"OK"}},
+		{name: "all read", method: "GET", path: contents + readerReadingList + "?xt=" + readerRead, auth: true,
+			notWant: []string{item1, item2, item3}},
+	})
+}
+
+// TestReaderSubscribeFindsFeed checks that subscribing goes through feed
+// discovery and redirects like addfeed does.
+func TestReaderSubscribeFindsFeed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blog/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><link rel="alternate" type="application/rss+xml" href="/rss.xml"></head></html>`)
+	})
+	mux.HandleFunc("/rss.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		io.WriteString(w, `<rss version="2.0"><channel><title>Found</title><link>https://found.example/</link></channel></rss>`)
+	})
+	mux.Handle("/old.xml", http.RedirectHandler("/rss.xml", http.StatusMovedPermanently))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	db := newSyncFixture()
+	s := newTestState(db)
+	s.http = newTestHTTPClient(t, config.HTTPConfig{})
+	replay(t, newServeMux(s), []replayStep{
+		{name: "quickadd a web page", method: "POST", path: "/reader/api/0/subscription/quickadd", auth: true,
+			form: url.Values{"quickadd": {srv.URL + "/blog/"}},
+			want: []string{`"numResults":1`, `"query":"` + srv.URL + `/rss.xml"`, `"streamName":"Found"`}},
+		{name: "subscribe to a moved feed", method: "POST", path: "/reader/api/0/subscription/edit", auth: true,
+			form: url.Values{"ac": {"subscribe"}, "s": {readerFeedPrefix + srv.URL + "/old.xml"}}, want: []string{"OK"}},
+		{name: "subscribe to a page without a feed", method: "POST", path: "/reader/api/0/subscription/edit", auth: true,
+			form: url.Values{"ac": {"subscribe"}, "s": {readerFeedPrefix + srv.URL + "/nothing"}}, wantStatus: http.StatusBadRequest},
+	})
+	var urls []string
+	for _, feed := range db.feeds {
+		if strings.HasPrefix(feed.Url, srv.URL) {
+			urls = append(urls, feed.Url)
+		}
+	}
+	if len(urls) != 1 || urls[0] != srv.URL+"/rss.xml" {
+		t.Errorf("stored feeds %q, want only the discovered one", urls)
+	}
+}
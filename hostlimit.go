@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHostConcurrency = 2
+	defaultHostDelay       = time.Second
+
+	// defaultTooManyRequestsBackoff applies to a 429 without Retry-After.
+	defaultTooManyRequestsBackoff = time.Minute
+	// maxRetryAfter caps how long a server can ask us to stay away.
+	maxRetryAfter = 6 * time.Hour
+)
+
+// hostLimiter keeps gator polite towards each server: at most concurrency
+// requests in flight per host, starting at least delay apart, and none at
+// all while the host has asked us to come back later (Retry-After on a 429
+// or 503).
+type hostLimiter struct {
+	concurrency int
+	delay       time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots   chan struct{}
+	next    time.Time // earliest start of the next request
+	retryAt time.Time // set from Retry-After
+}
+
+func newHostLimiter(concurrency int, delay time.Duration) *hostLimiter {
+	return &hostLimiter{concurrency: concurrency, delay: delay, hosts: map[string]*hostState{}}
+}
+
+func (l *hostLimiter) host(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostState{slots: make(chan struct{}, l.concurrency)}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// acquire waits for the host's turn and returns the function that ends the
+// request. A host that is backing off fails straight away rather than
+// stalling the caller for what may be hours.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	h := l.host(strings.ToLower(host))
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-h.slots }
+
+	l.mu.Lock()
+	now := time.Now()
+	if h.retryAt.After(now) {
+		retryAt := h.retryAt
+		l.mu.Unlock()
+		release()
+		return nil, fmt.Errorf("%s asked to be left alone until %s", host, retryAt.Format(time.TimeOnly))
+	}
+	start := now
+	if h.next.After(start) {
+		start = h.next
+	}
+	h.next = start.Add(l.delay)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// observe records a Retry-After from a 429 or 503 response.
+func (l *hostLimiter) observe(host string, res *http.Response) {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+	now := time.Now()
+	wait := parseRetryAfter(res.Header.Get("Retry-After"), now)
+	if wait <= 0 && res.StatusCode == http.StatusTooManyRequests {
+		wait = defaultTooManyRequestsBackoff
+	}
+	if wait <= 0 {
+		return
+	}
+	h := l.host(strings.ToLower(host))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if retryAt := now.Add(min(wait, maxRetryAfter)); retryAt.After(h.retryAt) {
+		h.retryAt = retryAt
+	}
+}
+
+// limitedTransport applies a hostLimiter to every request it sends,
+// redirects included: each hop waits for its own host's turn and holds the
+// slot until its response body is closed.
+type limitedTransport struct {
+	next   http.RoundTripper
+	limits *hostLimiter
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limits.acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	t.limits.observe(req.URL.Host, res)
+	res.Body = &releasingBody{ReadCloser: res.Body, release: sync.OnceFunc(release)}
+	return res, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
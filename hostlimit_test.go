@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"gator/internal/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHostLimitsApplyToRedirects checks that a redirect hop waits for the
+// target host's limits, not just the first host's.
+func TestHostLimitsApplyToRedirects(t *testing.T) {
+	unblock := make(chan struct{})
+	var inFlight, maxInFlight atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		if r.URL.Path == "/slow" {
+			<-unblock
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer target.Close()
+	redirector := httptest.NewServer(http.RedirectHandler(target.URL+"/feed", http.StatusFound))
+	defer redirector.Close()
+
+	client := newTestHTTPClient(t, config.HTTPConfig{HostConcurrency: 1})
+	ctx := context.Background()
+
+	slow := make(chan error, 1)
+	go func() {
+		_, _, err := client.fetch(ctx, target.URL+"/slow", "", nil)
+		slow <- err
+	}()
+	for inFlight.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	redirected := make(chan error, 1)
+	go func() {
+		_, _, err := client.fetch(ctx, redirector.URL, "", nil)
+		redirected <- err
+	}()
+	select {
+	case err := <-redirected:
+		t.Fatalf("redirect to a busy host finished early: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(unblock)
+	for _, done := range []chan error{slow, redirected} {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := maxInFlight.Load(); n != 1 {
+		t.Errorf("%d requests in flight to one host, want at most 1", n)
+	}
+}
+
+func TestHostLimitsRetryAfterOnRedirect(t *testing.T) {
+	var hits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer target.Close()
+	redirector := httptest.NewServer(http.RedirectHandler(target.URL+"/feed", http.StatusFound))
+	defer redirector.Close()
+
+	client := newTestHTTPClient(t, config.HTTPConfig{})
+	ctx := context.Background()
+	_, _, err := client.fetch(ctx, redirector.URL, "", nil)
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("first fetch: %v, want a 429", err)
+	}
+
+	// The target asked to be left alone, so neither a direct request nor
+	// one redirected to it may reach it.
+	for _, u := range []string{target.URL + "/feed", redirector.URL} {
+		_, _, err := client.fetch(ctx, u, "", nil)
+		if err == nil || !strings.Contains(err.Error(), "left alone") {
+			t.Errorf("fetch %s: %v, want the host to be backing off", u, err)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("target hit %d times, want 1", n)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"120", 2 * time.Minute},
+		{" 5 ", 5 * time.Second},
+		{"Wed, 01 May 2024 12:30:00 GMT", 30 * time.Minute},
+		{"", 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+)
+
+// htmlToText renders an HTML fragment (a feed item's description) as plain
+// text wrapped to width columns. Links are replaced by [n] markers and
+// returned separately so the caller can print them as footnotes.
+func htmlToText(src string, width int) (string, []string) {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return strings.Join(wrapText(stripTags(src), width), "\n"), nil
+	}
+	r := &textRenderer{width: width}
+	r.walk(doc)
+	r.flush(true)
+	return strings.TrimRight(r.out.String(), "\n"), r.links
+}
+
+type textRenderer struct {
+	width  int
+	out    strings.Builder
+	inline strings.Builder
+	links  []string
+
+	quote        string // "> " per enclosing blockquote
+	indent       string // two spaces per enclosing list
+	bullet       string // marker for the first line of the current list item
+	pre          int
+	pendingBlank bool
+	started      bool  // something was written since the last blank line
+	lists        []int // item counter per enclosing list; -1 for <ul>
+}
+
+func (r *textRenderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.inline.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.walkChildren(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Noscript:
+	case atom.Br:
+		if r.pre > 0 {
+			r.inline.WriteByte('\n')
+		} else {
+			r.flush(false)
+		}
+	case atom.A:
+		r.walkChildren(n)
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			return
+		}
+		r.links = append(r.links, href)
+		fmt.Fprintf(&r.inline, "[%d]", len(r.links))
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			fmt.Fprintf(&r.inline, " [image: %s] ", alt)
+		}
+	case atom.Blockquote:
+		r.flush(true)
+		r.separate()
+		saved := r.quote
+		r.quote += "> "
+		r.walkChildren(n)
+		r.flush(true)
+		r.quote = saved
+	case atom.Ul, atom.Ol:
+		r.flush(len(r.lists) == 0)
+		start := -1
+		if n.DataAtom == atom.Ol {
+			start = 0
+		}
+		r.lists = append(r.lists, start)
+		r.walkChildren(n)
+		r.flush(false)
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.pendingBlank = true
+		}
+	case atom.Li:
+		r.flush(false)
+		saved := r.indent
+		if depth := len(r.lists); depth > 0 {
+			r.indent = strings.Repeat("  ", depth-1)
+			if r.lists[depth-1] >= 0 {
+				r.lists[depth-1]++
+				r.bullet = fmt.Sprintf("%d. ", r.lists[depth-1])
+			} else {
+				r.bullet = "• "
+			}
+		}
+		r.walkChildren(n)
+		r.flush(false)
+		r.indent = saved
+	case atom.Pre:
+		r.flush(true)
+		r.pre++
+		r.walkChildren(n)
+		r.pre--
+		r.separate()
+		for _, line := range strings.Split(strings.Trim(r.inline.String(), "\n"), "\n") {
+			r.writeLine(r.quote + "    " + line)
+		}
+		r.inline.Reset()
+		r.pendingBlank = true
+	case atom.Hr:
+		r.flush(true)
+		r.separate()
+		r.writeLine(r.quote + strings.Repeat("─", min(r.width, 40)))
+		r.pendingBlank = true
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Table, atom.Tr, atom.Section, atom.Article, atom.Header, atom.Footer,
+		atom.Figure, atom.Figcaption, atom.Dl, atom.Dt, atom.Dd:
+		r.flush(true)
+		r.walkChildren(n)
+		r.flush(true)
+	default:
+		r.walkChildren(n)
+	}
+}
+
+func (r *textRenderer) walkChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c)
+	}
+}
+
+// flush wraps the pending inline text into a paragraph. blank asks for an
+// empty line before whatever is written next.
+func (r *textRenderer) flush(blank bool) {
+	text := strings.Join(strings.Fields(r.inline.String()), " ")
+	r.inline.Reset()
+	if text != "" {
+		r.separate()
+		lead := r.quote + r.indent
+		hang := strings.Repeat(" ", len([]rune(r.bullet)))
+		for i, line := range wrapText(text, max(r.width-len([]rune(lead+hang)), 20)) {
+			if i == 0 {
+				r.writeLine(lead + r.bullet + line)
+			} else {
+				r.writeLine(lead + hang + line)
+			}
+		}
+		r.bullet = ""
+	}
+	if blank {
+		r.pendingBlank = true
+	}
+}
+
+// separate writes the blank line a previous block asked for.
+func (r *textRenderer) separate() {
+	if r.pendingBlank && r.started {
+		r.writeLine(r.quote)
+	}
+	r.pendingBlank = false
+	r.started = false
+}
+
+func (r *textRenderer) writeLine(line string) {
+	r.out.WriteString(strings.TrimRight(line, " "))
+	r.out.WriteByte('\n')
+	r.started = true
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"gator/internal/config"
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/http/httpproxy"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultHTTPTimeout    = 30 * time.Second
+	defaultMaxBodySize    = 10 << 20
+	defaultMaxRedirects   = 5
+)
+
+// httpClient is the one HTTP client gator fetches feeds, web pages and
+// podcast episodes with. Every request has a connect timeout and a capped
+// number of redirects; fetch additionally bounds the whole request in time
+// and the (decompressed) body in size, which downloads can't.
+type httpClient struct {
+	client      *http.Client
+	download    *http.Client
+	policy      *urlPolicy
+	maxBodySize int64
+}
+
+// httpStatusError is a response other than the one asked for.
+type httpStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %s from %s", e.Status, e.URL)
+}
+
+var errBodyTooLarge = errors.New("response body too large")
+
+// extraHeaderKey holds, in a request's context, the names of the headers
+// fetch was asked to add.
+type extraHeaderKey struct{}
+
+func newHTTPClient(cfg *config.HTTPConfig) (*httpClient, error) {
+	if cfg == nil {
+		cfg = &config.HTTPConfig{}
+	}
+	connectTimeout, err := configDuration("http.connect_timeout", cfg.ConnectTimeout, defaultConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := configDuration("http.timeout", cfg.Timeout, defaultHTTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	maxBodySize := cfg.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+	maxRedirects := cfg.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	hostConcurrency := cfg.HostConcurrency
+	if hostConcurrency <= 0 {
+		hostConcurrency = defaultHostConcurrency
+	}
+	hostDelay, err := configDuration("http.host_delay", cfg.HostDelay, defaultHostDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := newURLPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = policy.dialContext(&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second})
+	transport.TLSHandshakeTimeout = connectTimeout
+	if cfg.Proxy != "" {
+		proxy := (&httpproxy.Config{HTTPProxy: cfg.Proxy, HTTPSProxy: cfg.Proxy, NoProxy: cfg.NoProxy}).ProxyFunc()
+		transport.Proxy = func(req *http.Request) (*url.URL, error) { return proxy(req.URL) }
+	}
+	transport.Proxy = policy.proxy(transport.Proxy)
+	if transport.TLSClientConfig, err = tlsConfig(cfg); err != nil {
+		return nil, err
+	}
+	checkRedirect := func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if err := policy.checkURL(req.URL); err != nil {
+			return err
+		}
+		// net/http already drops Authorization and Cookie when leaving the
+		// original host; do the same for any other header of a feed's.
+		if names, ok := req.Context().Value(extraHeaderKey{}).([]string); ok && req.URL.Host != via[0].URL.Host {
+			for _, name := range names {
+				req.Header.Del(name)
+			}
+		}
+		return nil
+	}
+	limited := &limitedTransport{next: transport, limits: newHostLimiter(hostConcurrency, hostDelay)}
+	return &httpClient{
+		client:      &http.Client{Transport: limited, Timeout: timeout, CheckRedirect: checkRedirect},
+		download:    &http.Client{Transport: limited, CheckRedirect: checkRedirect},
+		policy:      policy,
+		maxBodySize: maxBodySize,
+	}, nil
+}
+
+// tlsConfig adds the configured CA bundles and client certificate to the
+// default TLS settings.
+func tlsConfig(cfg *config.HTTPConfig) (*tls.Config, error) {
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(cfg.CAFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range cfg.CAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", file)
+			}
+		}
+		tc.RootCAs = pool
+	}
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		if cfg.ClientCert == "" || cfg.ClientKey == "" {
+			return nil, errors.New("http.client_cert and http.client_key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
+func configDuration(key, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q in config (use a duration such as 10s)", key, value)
+	}
+	return d, nil
+}
+
+// permanentRedirect returns the URL res was finally fetched from if it was
+// reached only through permanent redirects, and "" otherwise.
+func permanentRedirect(res *http.Response) string {
+	req := res.Request
+	if req.Response == nil {
+		return ""
+	}
+	for r := req; r.Response != nil; r = r.Response.Request {
+		switch r.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		default:
+			return ""
+		}
+	}
+	return req.URL.String()
+}
+
+// send performs req with hc. The transport holds every hop, redirects
+// included, to its host's limits until the response body is closed.
+func (c *httpClient) send(hc *http.Client, req *http.Request) (*http.Response, error) {
+	if err := c.policy.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	return hc.Do(req)
+}
+
+// newGetRequest builds a GET request for rawURL with any extra header,
+// whose names are noted so that redirects to other hosts drop them.
+func newGetRequest(ctx context.Context, rawURL string, header http.Header) (*http.Request, error) {
+	if len(header) > 0 {
+		names := make([]string, 0, len(header))
+		for name := range header {
+			names = append(names, name)
+		}
+		ctx = context.WithValue(ctx, extraHeaderKey{}, names)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", "gator")
+	return req, nil
+}
+
+// fetch GETs rawURL, with any extra request header, and returns its body,
+// decompressed, along with the response (whose body is already closed).
+// Anything but 200 OK is an *httpStatusError, and a body over the size limit
+// is errBodyTooLarge.
+func (c *httpClient) fetch(ctx context.Context, rawURL, accept string, header http.Header) ([]byte, *http.Response, error) {
+	req, err := newGetRequest(ctx, rawURL, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	res, err := c.send(c.client, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, res, &httpStatusError{URL: rawURL, StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	var body io.Reader = res.Body
+	switch strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(res.Body)
+		if err != nil {
+			return nil, res, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	case "br":
+		body = brotli.NewReader(res.Body)
+	default:
+		return nil, res, fmt.Errorf("unsupported content encoding %q", res.Header.Get("Content-Encoding"))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, c.maxBodySize+1))
+	if err != nil {
+		return nil, res, err
+	}
+	if int64(len(data)) > c.maxBodySize {
+		return nil, res, fmt.Errorf("%w: %s is over %s", errBodyTooLarge, rawURL, formatSize(c.maxBodySize))
+	}
+	return data, res, nil
+}
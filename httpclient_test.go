@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"gator/internal/config"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writePEM writes one PEM block to a file in dir and returns its path.
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// serverCAFile saves the certificate of a TLS test server as a CA bundle.
+func serverCAFile(t *testing.T, srv *httptest.Server) string {
+	return writePEM(t, t.TempDir(), "server-ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+}
+
+// newClientCA creates a CA and a client certificate it signed, returning
+// the CA pool and the client key pair's files.
+func newClientCA(t *testing.T) (pool *x509.CertPool, certFile, keyFile string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gator test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "gator"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, ca, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	pool = x509.NewCertPool()
+	pool.AddCert(ca)
+	return pool, writePEM(t, dir, "client.pem", "CERTIFICATE", clientDER), writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func TestHTTPClientCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "private feed")
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	_, _, err := newTestHTTPClient(t, config.HTTPConfig{}).fetch(ctx, srv.URL, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("server with a private CA accepted without the CA bundle: %v", err)
+	}
+
+	client := newTestHTTPClient(t, config.HTTPConfig{CAFiles: []string{serverCAFile(t, srv)}})
+	body, _, err := client.fetch(ctx, srv.URL, "", nil)
+	if err != nil || string(body) != "private feed" {
+		t.Errorf("with the CA bundle: %q, %v", body, err)
+	}
+}
+
+func TestHTTPClientMutualTLS(t *testing.T) {
+	pool, certFile, keyFile := newClientCA(t)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello "+r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+	caFile := serverCAFile(t, srv)
+	ctx := context.Background()
+
+	_, _, err := newTestHTTPClient(t, config.HTTPConfig{CAFiles: []string{caFile}}).fetch(ctx, srv.URL, "", nil)
+	if err == nil {
+		t.Error("server requiring a client certificate accepted a client without one")
+	}
+
+	client := newTestHTTPClient(t, config.HTTPConfig{CAFiles: []string{caFile}, ClientCert: certFile, ClientKey: keyFile})
+	body, _, err := client.fetch(ctx, srv.URL, "", nil)
+	if err != nil || string(body) != "hello gator" {
+		t.Errorf("with a client certificate: %q, %v", body, err)
+	}
+}
+
+func TestHTTPClientTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, certFile, _ := newClientCA(t)
+	tests := []struct {
+		name string
+		cfg  config.HTTPConfig
+		want string
+	}{
+		{"missing CA bundle", config.HTTPConfig{CAFiles: []string{filepath.Join(dir, "nope.pem")}}, "failed to read CA bundle"},
+		{"empty CA bundle", config.HTTPConfig{CAFiles: []string{notPEM}}, "no certificates found"},
+		{"cert without key", config.HTTPConfig{ClientCert: certFile}, "must be set together"},
+		{"key that isn't one", config.HTTPConfig{ClientCert: certFile, ClientKey: notPEM}, "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		if _, err := newHTTPClient(&tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+// testProxy is a forward proxy for plain HTTP requests and CONNECT tunnels.
+// It sends every request to target, whatever host the client asked for, and
+// records what it was asked for.
+type testProxy struct {
+	target string // host:port
+
+	mu   sync.Mutex
+	seen []string
+}
+
+func (p *testProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.seen = append(p.seen, r.Method+" "+r.Host)
+	p.mu.Unlock()
+
+	if r.Method == http.MethodConnect {
+		upstream, err := net.Dial("tcp", p.target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, buf)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+		return
+	}
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.URL.Host = p.target
+	res, err := http.DefaultTransport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+	w.WriteHeader(res.StatusCode)
+	io.Copy(w, res.Body)
+}
+
+func (p *testProxy) requests() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.seen...)
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "via "+r.Host)
+	}))
+	defer origin.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secure via "+r.Host)
+	}))
+	defer secure.Close()
+
+	plainProxy := &testProxy{target: origin.Listener.Addr().String()}
+	plainSrv := httptest.NewServer(plainProxy)
+	defer plainSrv.Close()
+	tunnelProxy := &testProxy{target: secure.Listener.Addr().String()}
+	tunnelSrv := httptest.NewServer(tunnelProxy)
+	defer tunnelSrv.Close()
+	ctx := context.Background()
+
+	// Plain HTTP goes to the proxy as an absolute-form request, and the
+	// proxy, not gator, resolves the name.
+	client := newTestHTTPClient(t, config.HTTPConfig{Proxy: plainSrv.URL, NoProxy: "direct.example"})
+	body, _, err := client.fetch(ctx, "http://feeds.example/feed.xml", "", nil)
+	if err != nil || string(body) != "via feeds.example" {
+		t.Errorf("proxied fetch: %q, %v", body, err)
+	}
+	if got := plainProxy.requests(); len(got) != 1 || got[0] != "GET feeds.example" {
+		t.Errorf("proxy saw %q", got)
+	}
+	// no_proxy hosts are dialled directly, and direct.example doesn't exist.
+	if _, _, err := client.fetch(ctx, "http://direct.example/feed.xml", "", nil); err == nil {
+		t.Error("no_proxy host was fetched through the proxy")
+	}
+	if got := plainProxy.requests(); len(got) != 1 {
+		t.Errorf("proxy saw %q after a no_proxy request", got)
+	}
+
+	// HTTPS is tunnelled with CONNECT and verified end to end against the
+	// CA bundle; httptest's certificate is valid for example.com.
+	port := secure.Listener.Addr().(*net.TCPAddr).Port
+	secureURL := (&url.URL{Scheme: "https", Host: net.JoinHostPort("example.com", strconv.Itoa(port)), Path: "/feed.xml"}).String()
+	client = newTestHTTPClient(t, config.HTTPConfig{Proxy: tunnelSrv.URL, CAFiles: []string{serverCAFile(t, secure)}})
+	body, _, err = client.fetch(ctx, secureURL, "", nil)
+	if err != nil || !strings.HasPrefix(string(body), "secure via example.com") {
+		t.Errorf("tunnelled fetch: %q, %v", body, err)
+	}
+	if got := tunnelProxy.requests(); len(got) != 1 || got[0] != "CONNECT example.com:"+strconv.Itoa(port) {
+		t.Errorf("proxy saw %q", got)
+	}
+}
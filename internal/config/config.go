@@ -10,8 +10,39 @@ import (
 const configFileName = ".gatorconfig.json"
 
 type Config struct {
-	DbUrl       string `json:"db_url"`
-	CurrentUser string `json:"current_user"`
+	DbUrl       string      `json:"db_url"`
+	CurrentUser string      `json:"current_user"`
+	HTTP        *HTTPConfig `json:"http,omitempty"`
+	// CredentialsKey is the base64 AES-256 key feed credentials are
+	// encrypted with; it is generated the first time one is stored.
+	CredentialsKey string `json:"credentials_key,omitempty"`
+}
+
+// HTTPConfig tunes the client used to fetch feeds, articles and podcast
+// episodes. Durations are Go duration strings such as "10s"; zero values
+// mean the defaults.
+type HTTPConfig struct {
+	ConnectTimeout string `json:"connect_timeout,omitempty"`
+	Timeout        string `json:"timeout,omitempty"`
+	MaxBodySize    int64  `json:"max_body_size,omitempty"`
+	MaxRedirects   int    `json:"max_redirects,omitempty"`
+	// Politeness towards any single server.
+	HostConcurrency int    `json:"host_concurrency,omitempty"`
+	HostDelay       string `json:"host_delay,omitempty"`
+	// Proxy is used for every request unless the host matches NoProxy
+	// (same syntax as the NO_PROXY environment variable). Without it the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+	Proxy   string `json:"proxy,omitempty"`
+	NoProxy string `json:"no_proxy,omitempty"`
+	// CAFiles are PEM bundles trusted in addition to the system roots;
+	// ClientCert and ClientKey are a PEM key pair for mutual TLS.
+	CAFiles    []string `json:"ca_files,omitempty"`
+	ClientCert string   `json:"client_cert,omitempty"`
+	ClientKey  string   `json:"client_key,omitempty"`
+	// Feeds are only fetched from public addresses, except for these host
+	// names and CIDR networks.
+	AllowHosts    []string `json:"allow_hosts,omitempty"`
+	AllowNetworks []string `json:"allow_networks,omitempty"`
 }
 
 func getUserHomeDir() (string, error) {
@@ -39,6 +70,15 @@ func Read() (Config, error) {
 
 func (cfg *Config) SetUser(user string) error {
 	cfg.CurrentUser = user
+	return cfg.write()
+}
+
+func (cfg *Config) SetCredentialsKey(key string) error {
+	cfg.CredentialsKey = key
+	return cfg.write()
+}
+
+func (cfg *Config) write() error {
 	homeDir, err := getUserHomeDir()
 	if err != nil {
 		return err
@@ -55,6 +95,13 @@ func (cfg *Config) SetUser(user string) error {
 		}
 	}(file)
 
+	if cfg.CredentialsKey != "" {
+		// The key decrypts every stored feed credential.
+		if err := file.Chmod(0o600); err != nil {
+			return err
+		}
+	}
+
 	encoder := json.NewEncoder(file)
 	err = encoder.Encode(cfg)
 	if err != nil {
@@ -7,31 +7,36 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const addFeed = `-- name: AddFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, link, description)
 VALUES (
         $1,
         $2,
         $3,
         $4,
         $5,
-        $6
+        $6,
+        $7,
+        $8
        )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, seq, fetch_full_content, link, description, moved_to, moved_count, gone_at
 `
 
 type AddFeedParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string
+	Url         string
+	UserID      uuid.UUID
+	Link        sql.NullString
+	Description sql.NullString
 }
 
 func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error) {
@@ -42,6 +47,8 @@ func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error)
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.Link,
+		arg.Description,
 	)
 	var i Feed
 	err := row.Scan(
@@ -52,10 +59,30 @@ func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error)
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Seq,
+		&i.FetchFullContent,
+		&i.Link,
+		&i.Description,
+		&i.MovedTo,
+		&i.MovedCount,
+		&i.GoneAt,
 	)
 	return i, err
 }
 
+const clearFeedRedirect = `-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET moved_to = NULL,
+    moved_count = 0
+WHERE id = $1
+  AND moved_to IS NOT NULL
+`
+
+func (q *Queries) ClearFeedRedirect(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, clearFeedRedirect, id)
+	return err
+}
+
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_follow AS (
     INSERT INTO feed_follows (user_id, feed_id)
@@ -101,6 +128,16 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const deleteFeeds = `-- name: DeleteFeeds :exec
 DELETE FROM feeds
 `
@@ -119,8 +156,62 @@ func (q *Queries) DeleteFollows(ctx context.Context) error {
 	return err
 }
 
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq, fetch_full_content, link, description, moved_to, moved_count, gone_at FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Seq,
+		&i.FetchFullContent,
+		&i.Link,
+		&i.Description,
+		&i.MovedTo,
+		&i.MovedCount,
+		&i.GoneAt,
+	)
+	return i, err
+}
+
+const getFeedBySeq = `-- name: GetFeedBySeq :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq, fetch_full_content, link, description, moved_to, moved_count, gone_at FROM feeds
+WHERE seq = $1
+`
+
+func (q *Queries) GetFeedBySeq(ctx context.Context, seq int64) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedBySeq, seq)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Seq,
+		&i.FetchFullContent,
+		&i.Link,
+		&i.Description,
+		&i.MovedTo,
+		&i.MovedCount,
+		&i.GoneAt,
+	)
+	return i, err
+}
+
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq, fetch_full_content, link, description, moved_to, moved_count, gone_at FROM feeds
 WHERE url = $1
 `
 
@@ -135,6 +226,13 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Seq,
+		&i.FetchFullContent,
+		&i.Link,
+		&i.Description,
+		&i.MovedTo,
+		&i.MovedCount,
+		&i.GoneAt,
 	)
 	return i, err
 }
@@ -142,7 +240,8 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
        users.name as user_name,
-       feeds.name as feed_name
+       feeds.name as feed_name,
+       feeds.url as feed_url
 FROM feed_follows
 JOIN users ON users.id = feed_follows.user_id
 JOIN feeds ON feeds.id = feed_follows.feed_id
@@ -157,6 +256,7 @@ type GetFeedFollowsForUserRow struct {
 	FeedID    uuid.UUID
 	UserName  string
 	FeedName  string
+	FeedUrl   string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -176,6 +276,7 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.FeedID,
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -191,25 +292,80 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT name, url, user_id FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq, fetch_full_content, link, description, moved_to, moved_count, gone_at FROM feeds
 `
 
-type GetFeedsRow struct {
-	Name   string
-	Url    string
-	UserID uuid.UUID
+func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Seq,
+			&i.FetchFullContent,
+			&i.Link,
+			&i.Description,
+			&i.MovedTo,
+			&i.MovedCount,
+			&i.GoneAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getFeeds)
+const getFeedsForUser = `-- name: GetFeedsForUser :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.seq, feeds.fetch_full_content, feeds.link, feeds.description, feeds.moved_to, feeds.moved_count, feeds.gone_at
+FROM feeds
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.name
+`
+
+func (q *Queries) GetFeedsForUser(ctx context.Context, userID uuid.UUID) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsForUser, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetFeedsRow
+	var items []Feed
 	for rows.Next() {
-		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.UserID); err != nil {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Seq,
+			&i.FetchFullContent,
+			&i.Link,
+			&i.Description,
+			&i.MovedTo,
+			&i.MovedCount,
+			&i.GoneAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -223,26 +379,57 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq, fetch_full_content, link, description, moved_to, moved_count, gone_at
 FROM feeds
+WHERE gone_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM websub_subscriptions AS ws
+    WHERE ws.feed_id = feeds.id
+    AND ws.state = 'active'
+    AND ws.lease_expires_at > NOW()
+    AND feeds.last_fetched_at > NOW() - INTERVAL '1 day'
+)
 ORDER BY last_fetched_at NULLS FIRST,
-         updated_at LIMIT 1
+         updated_at LIMIT $1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Seq,
+			&i.FetchFullContent,
+			&i.Link,
+			&i.Description,
+			&i.MovedTo,
+			&i.MovedCount,
+			&i.GoneAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
@@ -257,6 +444,103 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const markFeedGone = `-- name: MarkFeedGone :exec
+UPDATE feeds
+SET gone_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkFeedGone(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markFeedGone, id)
+	return err
+}
+
+const mergeFeed = `-- name: MergeFeed :exec
+WITH moved_follows AS (
+    INSERT INTO feed_follows (user_id, feed_id)
+    SELECT user_id, $1::uuid
+    FROM feed_follows
+    WHERE feed_id = $2
+    ON CONFLICT (user_id, feed_id) DO NOTHING
+), full_content AS (
+    UPDATE feeds
+    SET fetch_full_content = true,
+        updated_at = NOW()
+    WHERE id = $1
+    AND (SELECT fetch_full_content FROM feeds WHERE id = $2)
+)
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+`
+
+type MergeFeedParams struct {
+	IntoID uuid.UUID
+	FromID uuid.UUID
+}
+
+func (q *Queries) MergeFeed(ctx context.Context, arg MergeFeedParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeed, arg.IntoID, arg.FromID)
+	return err
+}
+
+const recordFeedRedirect = `-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET moved_count = CASE WHEN moved_to = $2 THEN moved_count + 1 ELSE 1 END,
+    moved_to = $2
+WHERE id = $1
+RETURNING moved_count
+`
+
+type RecordFeedRedirectParams struct {
+	ID      uuid.UUID
+	MovedTo sql.NullString
+}
+
+func (q *Queries) RecordFeedRedirect(ctx context.Context, arg RecordFeedRedirectParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedRedirect, arg.ID, arg.MovedTo)
+	var moved_count int32
+	err := row.Scan(&moved_count)
+	return moved_count, err
+}
+
+const setFeedFetchFullContent = `-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET fetch_full_content = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedFetchFullContentParams struct {
+	ID               uuid.UUID
+	FetchFullContent bool
+}
+
+func (q *Queries) SetFeedFetchFullContent(ctx context.Context, arg SetFeedFetchFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchFullContent, arg.ID, arg.FetchFullContent)
+	return err
+}
+
+const setFeedURL = `-- name: SetFeedURL :exec
+UPDATE feeds
+SET url = $2,
+    moved_to = NULL,
+    moved_count = 0,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedURLParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) SetFeedURL(ctx context.Context, arg SetFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedURL, arg.ID, arg.Url)
+	return err
+}
+
 const unFollow = `-- name: UnFollow :exec
 WITH deleted_follow AS (
     DELETE FROM feed_follows
@@ -282,3 +566,22 @@ func (q *Queries) UnFollow(ctx context.Context, arg UnFollowParams) error {
 	_, err := q.db.ExecContext(ctx, unFollow, arg.UserID, arg.FeedID)
 	return err
 }
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET link = $2,
+    description = $3,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Link        sql.NullString
+	Description sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata, arg.ID, arg.Link, arg.Description)
+	return err
+}
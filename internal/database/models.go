@@ -11,14 +11,49 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	FeverKey   sql.NullString
+	Kind       string
+}
+
+type EpisodeState struct {
+	UserID       uuid.UUID
+	PostID       uuid.UUID
+	DownloadedAt sql.NullTime
+	FilePath     sql.NullString
+	PlayedAt     sql.NullTime
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Name             string
+	Url              string
+	UserID           uuid.UUID
+	LastFetchedAt    sql.NullTime
+	Seq              int64
+	FetchFullContent bool
+	Link             sql.NullString
+	Description      sql.NullString
+	MovedTo          sql.NullString
+	MovedCount       int32
+	GoneAt           sql.NullTime
+}
+
+type FeedCredential struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Secret    []byte
 }
 
 type FeedFollow struct {
@@ -38,11 +73,49 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Seq         int64
+	Content     sql.NullString
+	Author      sql.NullString
+	OriginalUrl sql.NullString
+}
+
+type PostCategory struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostEnclosure struct {
+	PostID   uuid.UUID
+	Url      string
+	MimeType string
+	Length   int64
+	Duration int32
+	Episode  sql.NullInt32
+}
+
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
 }
 
 type User struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+}
+
+type WebsubSubscription struct {
+	FeedID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	State          string
+	RequestedAt    sql.NullTime
+	LeaseExpiresAt sql.NullTime
 }
@@ -11,11 +11,12 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, original_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 `
 
 type CreatePostParams struct {
@@ -27,6 +28,9 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Author      sql.NullString
+	OriginalUrl sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
@@ -39,12 +43,200 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Content,
+		arg.Author,
+		arg.OriginalUrl,
 	)
 	return err
 }
 
+const createPostCategory = `-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type CreatePostCategoryParams struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) CreatePostCategory(ctx context.Context, arg CreatePostCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, createPostCategory, arg.PostID, arg.Name)
+	return err
+}
+
+const createPostEnclosure = `-- name: CreatePostEnclosure :exec
+INSERT INTO post_enclosures (post_id, url, mime_type, length, duration, episode)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT DO NOTHING
+`
+
+type CreatePostEnclosureParams struct {
+	PostID   uuid.UUID
+	Url      string
+	MimeType string
+	Length   int64
+	Duration int32
+	Episode  sql.NullInt32
+}
+
+func (q *Queries) CreatePostEnclosure(ctx context.Context, arg CreatePostEnclosureParams) error {
+	_, err := q.db.ExecContext(ctx, createPostEnclosure,
+		arg.PostID,
+		arg.Url,
+		arg.MimeType,
+		arg.Length,
+		arg.Duration,
+		arg.Episode,
+	)
+	return err
+}
+
+const getCategoriesForPosts = `-- name: GetCategoriesForPosts :many
+SELECT post_id, name
+FROM post_categories
+WHERE post_id = ANY($1::uuid[])
+ORDER BY post_id, name
+`
+
+type GetCategoriesForPostsRow struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetCategoriesForPosts(ctx context.Context, postIds []uuid.UUID) ([]GetCategoriesForPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCategoriesForPosts, pq.Array(postIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCategoriesForPostsRow
+	for rows.Next() {
+		var i GetCategoriesForPostsRow
+		if err := rows.Scan(&i.PostID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEnclosuresForPost = `-- name: GetEnclosuresForPost :many
+SELECT post_id, url, mime_type, length, duration, episode FROM post_enclosures
+WHERE post_id = $1
+ORDER BY url
+`
+
+func (q *Queries) GetEnclosuresForPost(ctx context.Context, postID uuid.UUID) ([]PostEnclosure, error) {
+	rows, err := q.db.QueryContext(ctx, getEnclosuresForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostEnclosure
+	for rows.Next() {
+		var i PostEnclosure
+		if err := rows.Scan(
+			&i.PostID,
+			&i.Url,
+			&i.MimeType,
+			&i.Length,
+			&i.Duration,
+			&i.Episode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByIDPrefix = `-- name: GetPostsByIDPrefix :many
+SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id, p.seq, p.content, p.author, p.original_url, f.name AS feed_name, f.url AS feed_url
+FROM posts AS p
+JOIN feeds AS f ON f.id = p.feed_id
+JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
+WHERE ff.user_id = $1
+AND p.id::text LIKE $2::text || '%'
+ORDER BY p.published_at DESC NULLS LAST
+LIMIT 10
+`
+
+type GetPostsByIDPrefixParams struct {
+	UserID uuid.UUID
+	Prefix string
+}
+
+type GetPostsByIDPrefixRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Seq         int64
+	Content     sql.NullString
+	Author      sql.NullString
+	OriginalUrl sql.NullString
+	FeedName    string
+	FeedUrl     string
+}
+
+func (q *Queries) GetPostsByIDPrefix(ctx context.Context, arg GetPostsByIDPrefixParams) ([]GetPostsByIDPrefixRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByIDPrefix, arg.UserID, arg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsByIDPrefixRow
+	for rows.Next() {
+		var i GetPostsByIDPrefixRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Seq,
+			&i.Content,
+			&i.Author,
+			&i.OriginalUrl,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id
+SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id, p.seq, p.content, p.author, p.original_url
 FROM posts AS p
 JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
 WHERE ff.user_id = $1
@@ -77,6 +269,75 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Seq,
+			&i.Content,
+			&i.Author,
+			&i.OriginalUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUserFiltered = `-- name: GetPostsForUserFiltered :many
+SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id, p.seq, p.content, p.author, p.original_url
+FROM posts AS p
+JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
+WHERE ff.user_id = $1
+AND ($2::text = '' OR p.author ILIKE '%' || $2::text || '%')
+AND ($3::text = '' OR EXISTS (
+    SELECT 1 FROM post_categories AS pc
+    WHERE pc.post_id = p.id
+    AND lower(pc.name) = lower($3::text)
+))
+ORDER BY
+    p.published_at DESC NULLS LAST,
+    p.created_at DESC
+LIMIT $4
+`
+
+type GetPostsForUserFilteredParams struct {
+	UserID   uuid.UUID
+	Author   string
+	Category string
+	MaxPosts int32
+}
+
+func (q *Queries) GetPostsForUserFiltered(ctx context.Context, arg GetPostsForUserFilteredParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserFiltered,
+		arg.UserID,
+		arg.Author,
+		arg.Category,
+		arg.MaxPosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Seq,
+			&i.Content,
+			&i.Author,
+			&i.OriginalUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -92,7 +353,7 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 }
 
 const getPostsForUserPaginated = `-- name: GetPostsForUserPaginated :many
-SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id
+SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id, p.seq, p.content, p.author, p.original_url
 FROM posts AS p
 JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
 WHERE ff.user_id = $1
@@ -105,10 +366,10 @@ OFFSET $4
 `
 
 type GetPostsForUserPaginatedParams struct {
-	UserID  uuid.UUID
-	Limit   int32
-	Sort string
-	Offset  int32
+	UserID uuid.UUID
+	Limit  int32
+	Sort   string
+	Offset int32
 }
 
 func (q *Queries) GetPostsForUserPaginated(ctx context.Context, arg GetPostsForUserPaginatedParams) ([]Post, error) {
@@ -134,6 +395,10 @@ func (q *Queries) GetPostsForUserPaginated(ctx context.Context, arg GetPostsForU
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Seq,
+			&i.Content,
+			&i.Author,
+			&i.OriginalUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -147,3 +412,20 @@ func (q *Queries) GetPostsForUserPaginated(ctx context.Context, arg GetPostsForU
 	}
 	return items, nil
 }
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetPostContentParams struct {
+	ID      uuid.UUID
+	Content sql.NullString
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.Content)
+	return err
+}
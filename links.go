@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters added by mailing lists, analytics and
+// ad networks; they never change what an article URL points to.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+}
+
+// itemBaseURL is what relative item links are resolved against: the
+// channel's <link>, itself resolved against the URL the feed was fetched
+// from.
+func itemBaseURL(feed *RSSFeed, feedURL string) *url.URL {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return nil
+	}
+	if link := strings.TrimSpace(feed.Channel.Link); link != "" {
+		if site, err := base.Parse(link); err == nil && site.IsAbs() {
+			return site
+		}
+	}
+	return base
+}
+
+// postURLs returns the URL to store an item under, canonicalized so that
+// the same article linked with different tracking parameters is stored
+// once, and the absolute link as the feed gave it if that differs.
+func postURLs(base *url.URL, link string) (string, sql.NullString) {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if link == "" || err != nil {
+		return link, sql.NullString{}
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	original := u.String()
+	canonical := canonicalURL(u)
+	return canonical, sql.NullString{String: original, Valid: original != canonical}
+}
+
+// canonicalURL lowercases the scheme and host, drops a default port, the
+// fragment and tracking parameters, and gives an empty path a "/". Other
+// query parameters are kept as they were, in order.
+func canonicalURL(u *url.URL) string {
+	c := *u
+	c.Scheme = strings.ToLower(c.Scheme)
+	c.Host = strings.ToLower(c.Host)
+	if port := c.Port(); (c.Scheme == "http" && port == "80") || (c.Scheme == "https" && port == "443") {
+		c.Host = strings.TrimSuffix(c.Host, ":"+port)
+	}
+	if c.Host != "" && c.Path == "" && c.RawPath == "" {
+		c.Path = "/"
+	}
+	c.Fragment, c.RawFragment = "", ""
+
+	if c.RawQuery != "" {
+		var kept []string
+		for _, param := range strings.Split(c.RawQuery, "&") {
+			name, _, _ := strings.Cut(param, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			name = strings.ToLower(name)
+			if param == "" || strings.HasPrefix(name, "utm_") || trackingParams[name] {
+				continue
+			}
+			kept = append(kept, param)
+		}
+		c.RawQuery = strings.Join(kept, "&")
+	}
+	c.ForceQuery = false
+	return c.String()
+}
+
+// postLink is the link to show for a post: the one the feed gave, falling
+// back to the canonical URL.
+func postLink(canonical string, original sql.NullString) string {
+	if original.Valid {
+		return original.String
+	}
+	return canonical
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"already canonical", "https://example.com/posts/1", "https://example.com/posts/1"},
+		{"scheme and host case", "HTTPS://Example.COM/Posts/1", "https://example.com/Posts/1"},
+		{"empty path", "https://example.com", "https://example.com/"},
+		{"http default port", "http://example.com:80/a", "http://example.com/a"},
+		{"https default port", "https://example.com:443/a", "https://example.com/a"},
+		{"other port kept", "https://example.com:8443/a", "https://example.com:8443/a"},
+		{"port default for the other scheme", "http://example.com:443/a", "http://example.com:443/a"},
+		{"fragment", "https://example.com/a#comments", "https://example.com/a"},
+		{"utm parameters", "https://example.com/a?utm_source=rss&utm_medium=feed", "https://example.com/a"},
+		{"tracking parameters", "https://example.com/a?fbclid=x&GCLID=y&mc_cid=z&_hsenc=w", "https://example.com/a"},
+		{"escaped tracking parameter", "https://example.com/a?utm%5Fsource=rss", "https://example.com/a"},
+		{"other parameters kept in order", "https://example.com/a?b=2&utm_campaign=x&a=1", "https://example.com/a?b=2&a=1"},
+		{"empty parameters", "https://example.com/a?&p=1&", "https://example.com/a?p=1"},
+		{"bare question mark", "https://example.com/a?", "https://example.com/a"},
+		{"utm lookalike kept", "https://example.com/a?utmost=1", "https://example.com/a?utmost=1"},
+		{"escaped path kept", "https://example.com/a%2Fb", "https://example.com/a%2Fb"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := canonicalURL(u); got != tt.want {
+			t.Errorf("%s: canonicalURL(%s) = %s, want %s", tt.name, tt.in, got, tt.want)
+		}
+		// Canonical URLs are their own canonical form.
+		again, _ := url.Parse(tt.want)
+		if got := canonicalURL(again); got != tt.want {
+			t.Errorf("%s: canonicalURL(%s) = %s, not stable", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestPostURLs(t *testing.T) {
+	feed := &RSSFeed{}
+	feed.Channel.Link = "https://blog.example/journal/"
+	base := itemBaseURL(feed, "https://feeds.example/blog.xml")
+	tests := []struct {
+		link, wantURL, wantOriginal string
+	}{
+		{"https://blog.example/journal/post", "https://blog.example/journal/post", ""},
+		{"post?utm_source=rss", "https://blog.example/journal/post", "https://blog.example/journal/post?utm_source=rss"},
+		{"/about#me", "https://blog.example/about", "https://blog.example/about#me"},
+		{"../2024/", "https://blog.example/2024/", ""},
+		{"//Cdn.Example:443/x", "https://cdn.example/x", "https://Cdn.Example:443/x"},
+		{"  https://other.example/a  ", "https://other.example/a", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		gotURL, gotOriginal := postURLs(base, tt.link)
+		if gotURL != tt.wantURL || gotOriginal.Valid != (tt.wantOriginal != "") || (gotOriginal.Valid && gotOriginal.String != tt.wantOriginal) {
+			t.Errorf("postURLs(%q) = %q, %+v; want %q, %q", tt.link, gotURL, gotOriginal, tt.wantURL, tt.wantOriginal)
+		}
+		wantLink := tt.wantOriginal
+		if wantLink == "" {
+			wantLink = tt.wantURL
+		}
+		if link := postLink(gotURL, gotOriginal); link != wantLink {
+			t.Errorf("postLink for %q = %q, want %q", tt.link, link, wantLink)
+		}
+	}
+}
+
+func TestItemBaseURL(t *testing.T) {
+	tests := []struct {
+		channelLink, feedURL, want string
+	}{
+		{"https://blog.example/", "https://feeds.example/rss", "https://blog.example/"},
+		{"/blog/", "https://example.com/feeds/rss.xml", "https://example.com/blog/"},
+		{"", "https://example.com/feeds/rss.xml", "https://example.com/feeds/rss.xml"},
+	}
+	for _, tt := range tests {
+		feed := &RSSFeed{}
+		feed.Channel.Link = tt.channelLink
+		if got := itemBaseURL(feed, tt.feedURL); got == nil || got.String() != tt.want {
+			t.Errorf("itemBaseURL(%q, %q) = %v, want %s", tt.channelLink, tt.feedURL, got, tt.want)
+		}
+	}
+}
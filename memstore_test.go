@@ -0,0 +1,605 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memStore is an in-memory stand-in for the database, implementing the
+// queries the handlers under test use. Any other query panics through the
+// nil embedded Querier, which points at what a new test needs to add here.
+type memStore struct {
+	database.Querier
+
+	mu      sync.Mutex
+	users   []database.User
+	tokens  []database.ApiToken
+	feeds   []database.Feed
+	follows map[uuid.UUID]map[uuid.UUID]bool // user -> feed
+	posts   []database.Post
+	states  map[[2]uuid.UUID]*memPostState // user, post
+	subs    map[uuid.UUID]*database.WebsubSubscription
+}
+
+type memPostState struct {
+	readAt    time.Time
+	starredAt time.Time
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		follows: map[uuid.UUID]map[uuid.UUID]bool{},
+		states:  map[[2]uuid.UUID]*memPostState{},
+		subs:    map[uuid.UUID]*database.WebsubSubscription{},
+	}
+}
+
+func (m *memStore) addUser(name string) database.User {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	user := database.User{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: name}
+	m.users = append(m.users, user)
+	return user
+}
+
+func (m *memStore) addToken(user database.User, token, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens = append(m.tokens, database.ApiToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      "test",
+		TokenHash: hashAPIToken(token),
+		FeverKey:  sql.NullString{String: hashFeverKey(feverKey(user.Name, token)), Valid: true},
+		Kind:      kind,
+	})
+}
+
+// addFeed adds a feed followed by user.
+func (m *memStore) addFeed(user database.User, name, url string) database.Feed {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	feed := database.Feed{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: name, Url: url,
+		UserID: user.ID, Seq: int64(len(m.feeds) + 1)}
+	m.feeds = append(m.feeds, feed)
+	if m.follows[user.ID] == nil {
+		m.follows[user.ID] = map[uuid.UUID]bool{}
+	}
+	m.follows[user.ID][feed.ID] = true
+	return feed
+}
+
+func (m *memStore) addPost(feed database.Feed, title, url string) database.Post {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	created := time.Now().UTC().Add(time.Duration(len(m.posts)-1000) * time.Second)
+	post := database.Post{ID: uuid.New(), CreatedAt: created, UpdatedAt: created, Title: title, Url: url,
+		Description: sql.NullString{String: "<p>" + title + "</p>", Valid: true},
+		FeedID:      feed.ID, Seq: int64(len(m.posts) + 1)}
+	m.posts = append(m.posts, post)
+	return post
+}
+
+func (m *memStore) userRow(token database.ApiToken) (database.GetUserByAPITokenRow, error) {
+	for _, user := range m.users {
+		if user.ID == token.UserID {
+			return database.GetUserByAPITokenRow{ID: user.ID, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt,
+				Name: user.Name, PasswordHash: user.PasswordHash, TokenID: token.ID, TokenExpiresAt: token.ExpiresAt, TokenKind: token.Kind}, nil
+		}
+	}
+	return database.GetUserByAPITokenRow{}, sql.ErrNoRows
+}
+
+func (m *memStore) GetUserByAPIToken(ctx context.Context, tokenHash string) (database.GetUserByAPITokenRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash {
+			return m.userRow(token)
+		}
+	}
+	return database.GetUserByAPITokenRow{}, sql.ErrNoRows
+}
+
+func (m *memStore) GetUserByFeverKey(ctx context.Context, feverKey sql.NullString) (database.GetUserByFeverKeyRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, token := range m.tokens {
+		if token.FeverKey == feverKey {
+			row, err := m.userRow(token)
+			return database.GetUserByFeverKeyRow(row), err
+		}
+	}
+	return database.GetUserByFeverKeyRow{}, sql.ErrNoRows
+}
+
+func (m *memStore) TouchAPIToken(ctx context.Context, arg database.TouchAPITokenParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.tokens {
+		if m.tokens[i].ID == arg.ID {
+			m.tokens[i].LastUsedAt = arg.LastUsedAt
+		}
+	}
+	return nil
+}
+
+func (m *memStore) GetFeedsForUser(ctx context.Context, userID uuid.UUID) ([]database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var feeds []database.Feed
+	for _, feed := range m.feeds {
+		if m.follows[userID][feed.ID] {
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds, nil
+}
+
+func (m *memStore) feed(id uuid.UUID) database.Feed {
+	for _, feed := range m.feeds {
+		if feed.ID == id {
+			return feed
+		}
+	}
+	return database.Feed{}
+}
+
+func (m *memStore) state(userID, postID uuid.UUID) *memPostState {
+	key := [2]uuid.UUID{userID, postID}
+	if m.states[key] == nil {
+		m.states[key] = &memPostState{}
+	}
+	return m.states[key]
+}
+
+// rows returns the posts userID follows, newest first, with their state.
+func (m *memStore) rows(userID uuid.UUID) []database.GetPostsWithStateForUserRow {
+	var rows []database.GetPostsWithStateForUserRow
+	for _, p := range m.posts {
+		if !m.follows[userID][p.FeedID] {
+			continue
+		}
+		feed, st := m.feed(p.FeedID), m.state(userID, p.ID)
+		rows = append(rows, database.GetPostsWithStateForUserRow{ID: p.ID, CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt, Title: p.Title, Url: p.Url, Description: p.Description,
+			PublishedAt: p.PublishedAt, FeedID: p.FeedID, Seq: p.Seq, Content: p.Content, Author: p.Author,
+			OriginalUrl: p.OriginalUrl, FeedSeq: feed.Seq, FeedName: feed.Name, FeedUrl: feed.Url,
+			IsRead: !st.readAt.IsZero(), IsStarred: !st.starredAt.IsZero()})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Seq > rows[j].Seq })
+	return rows
+}
+
+func (m *memStore) GetPostSeqsForUser(ctx context.Context, arg database.GetPostSeqsForUserParams) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var seqs []int64
+	for _, row := range m.rows(arg.UserID) {
+		if (arg.UnreadOnly && row.IsRead) || (arg.StarredOnly && !row.IsStarred) {
+			continue
+		}
+		seqs = append(seqs, row.Seq)
+	}
+	return seqs, nil
+}
+
+func (m *memStore) GetPostsWithStateBySeq(ctx context.Context, arg database.GetPostsWithStateBySeqParams) ([]database.GetPostsWithStateBySeqRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []database.GetPostsWithStateBySeqRow
+	for _, row := range m.rows(arg.UserID) {
+		for _, seq := range arg.Seqs {
+			if row.Seq == seq {
+				out = append(out, database.GetPostsWithStateBySeqRow(row))
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) GetPostsWithStateForUser(ctx context.Context, arg database.GetPostsWithStateForUserParams) ([]database.GetPostsWithStateForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []database.GetPostsWithStateForUserRow
+	for _, row := range m.rows(arg.UserID) {
+		switch {
+		case row.Seq <= arg.SinceSeq,
+			arg.MaxSeq != 0 && row.Seq >= arg.MaxSeq,
+			arg.FeedSeq != 0 && row.FeedSeq != arg.FeedSeq,
+			arg.UnreadOnly && row.IsRead,
+			arg.StarredOnly && !row.IsStarred,
+			arg.NewerThan.Valid && !row.CreatedAt.After(arg.NewerThan.Time):
+			continue
+		}
+		out = append(out, row)
+	}
+	if arg.OldestFirst {
+		sort.Slice(out, func(i, j int) bool { return out[i].Seq < out[j].Seq })
+	}
+	if len(out) > int(arg.MaxItems) {
+		out = out[:arg.MaxItems]
+	}
+	return out, nil
+}
+
+func (m *memStore) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if st := m.state(arg.UserID, arg.PostID); st.readAt.IsZero() {
+		st.readAt = time.Now()
+	}
+	return nil
+}
+
+func (m *memStore) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state(arg.UserID, arg.PostID).readAt = time.Time{}
+	return nil
+}
+
+func (m *memStore) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state(arg.UserID, arg.PostID).starredAt = time.Now()
+	return nil
+}
+
+func (m *memStore) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state(arg.UserID, arg.PostID).starredAt = time.Time{}
+	return nil
+}
+
+func (m *memStore) MarkPostsReadBefore(ctx context.Context, arg database.MarkPostsReadBeforeParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, row := range m.rows(arg.UserID) {
+		if row.CreatedAt.After(arg.Before) || (arg.FeedSeq != 0 && row.FeedSeq != arg.FeedSeq) {
+			continue
+		}
+		if st := m.state(arg.UserID, row.ID); st.readAt.IsZero() {
+			st.readAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *memStore) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.posts {
+		if m.posts[i].ID == arg.ID {
+			m.posts[i].Content = arg.Content
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// post returns the stored copy of the post with the given ID.
+func (m *memStore) post(id uuid.UUID) database.Post {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, post := range m.posts {
+		if post.ID == id {
+			return post
+		}
+	}
+	return database.Post{}
+}
+
+func (m *memStore) GetUserName(ctx context.Context, id uuid.UUID) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, user := range m.users {
+		if user.ID == id {
+			return user.Name, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+// follow makes user follow feed.
+func (m *memStore) follow(user database.User, feed database.Feed) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.follows[user.ID] == nil {
+		m.follows[user.ID] = map[uuid.UUID]bool{}
+	}
+	m.follows[user.ID][feed.ID] = true
+}
+
+func (m *memStore) GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if feed := m.feed(id); feed.ID == id {
+		return feed, nil
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+// CreatePost fails like the unique (feed_id, url) constraint does.
+func (m *memStore) CreatePost(ctx context.Context, arg database.CreatePostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, post := range m.posts {
+		if post.FeedID == arg.FeedID && post.Url == arg.Url {
+			return &pq.Error{Code: "23505"}
+		}
+	}
+	m.posts = append(m.posts, database.Post{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt,
+		Title: arg.Title, Url: arg.Url, Description: arg.Description, PublishedAt: arg.PublishedAt,
+		FeedID: arg.FeedID, Seq: int64(len(m.posts) + 1), Content: arg.Content, Author: arg.Author,
+		OriginalUrl: arg.OriginalUrl})
+	return nil
+}
+
+// addWebSub adds a subscription for feed to the hub at hubURL.
+func (m *memStore) addWebSub(feed database.Feed, hubURL, secret, state string, requestedAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs[feed.ID] = &database.WebsubSubscription{FeedID: feed.ID, HubUrl: hubURL, TopicUrl: feed.Url,
+		Secret: secret, State: state, RequestedAt: sql.NullTime{Time: requestedAt, Valid: !requestedAt.IsZero()}}
+}
+
+// webSub returns a copy of the subscription for feedID.
+func (m *memStore) webSub(feedID uuid.UUID) database.WebsubSubscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sub := m.subs[feedID]; sub != nil {
+		return *sub
+	}
+	return database.WebsubSubscription{}
+}
+
+func (m *memStore) GetWebSubSubscription(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sub := m.subs[feedID]; sub != nil {
+		return *sub, nil
+	}
+	return database.WebsubSubscription{}, sql.ErrNoRows
+}
+
+func (m *memStore) ActivateWebSub(ctx context.Context, arg database.ActivateWebSubParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub := m.subs[arg.FeedID]
+	if sub == nil || (sub.State != "pending" && sub.State != "active") ||
+		!sub.RequestedAt.Valid || sub.RequestedAt.Time.Before(time.Now().Add(-time.Hour)) {
+		return 0, nil
+	}
+	sub.State, sub.LeaseExpiresAt, sub.RequestedAt = "active", arg.LeaseExpiresAt, sql.NullTime{}
+	return 1, nil
+}
+
+func (m *memStore) DenyWebSub(ctx context.Context, feedID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sub := m.subs[feedID]; sub != nil {
+		sub.State = "denied"
+	}
+	return nil
+}
+
+func (m *memStore) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, feed := range m.feeds {
+		if feed.Url == url {
+			return feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (m *memStore) AddFeed(ctx context.Context, arg database.AddFeedParams) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, feed := range m.feeds {
+		if feed.Url == arg.Url {
+			return database.Feed{}, &pq.Error{Code: "23505"}
+		}
+	}
+	feed := database.Feed{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name,
+		Url: arg.Url, UserID: arg.UserID, Link: arg.Link, Description: arg.Description, Seq: int64(len(m.feeds) + 1)}
+	m.feeds = append(m.feeds, feed)
+	return feed, nil
+}
+
+func (m *memStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.follows[arg.UserID][arg.FeedID] {
+		return database.CreateFeedFollowRow{}, &pq.Error{Code: "23505"}
+	}
+	if m.follows[arg.UserID] == nil {
+		m.follows[arg.UserID] = map[uuid.UUID]bool{}
+	}
+	m.follows[arg.UserID][arg.FeedID] = true
+	return database.CreateFeedFollowRow{ID: uuid.New(), UserID: arg.UserID, FeedID: arg.FeedID,
+		FeedName: m.feed(arg.FeedID).Name}, nil
+}
+
+func (m *memStore) SetFeedFetchFullContent(ctx context.Context, arg database.SetFeedFetchFullContentParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.feeds {
+		if m.feeds[i].ID == arg.ID {
+			m.feeds[i].FetchFullContent = arg.FetchFullContent
+		}
+	}
+	return nil
+}
+
+func (m *memStore) GetPostsByIDPrefix(ctx context.Context, arg database.GetPostsByIDPrefixParams) ([]database.GetPostsByIDPrefixRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []database.GetPostsByIDPrefixRow
+	for _, row := range m.rows(arg.UserID) {
+		if strings.HasPrefix(row.ID.String(), arg.Prefix) {
+			out = append(out, database.GetPostsByIDPrefixRow{ID: row.ID, CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt, Title: row.Title, Url: row.Url, Description: row.Description,
+				PublishedAt: row.PublishedAt, FeedID: row.FeedID, Seq: row.Seq, Content: row.Content,
+				Author: row.Author, OriginalUrl: row.OriginalUrl, FeedName: row.FeedName, FeedUrl: row.FeedUrl})
+		}
+	}
+	if len(out) > 10 {
+		out = out[:10]
+	}
+	return out, nil
+}
+
+// setID gives the feed or post with ID old the ID id instead, for tests
+// about ID prefixes.
+func (m *memStore) setID(old, id uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.feeds {
+		if m.feeds[i].ID == old {
+			m.feeds[i].ID = id
+		}
+	}
+	for i := range m.posts {
+		if m.posts[i].ID == old {
+			m.posts[i].ID = id
+		}
+		if m.posts[i].FeedID == old {
+			m.posts[i].FeedID = id
+		}
+	}
+	for _, follows := range m.follows {
+		if follows[old] {
+			delete(follows, old)
+			follows[id] = true
+		}
+	}
+}
+
+// GetFeedCredentials finds none: the store holds no feed credentials, so
+// MoveFeedCredentials has nothing to move.
+func (m *memStore) GetFeedCredentials(ctx context.Context, feedID uuid.UUID) (database.FeedCredential, error) {
+	return database.FeedCredential{}, sql.ErrNoRows
+}
+
+func (m *memStore) MoveFeedCredentials(ctx context.Context, arg database.MoveFeedCredentialsParams) error {
+	return nil
+}
+
+// updateFeed applies f to the feed with ID id, if there is one.
+func (m *memStore) updateFeed(id uuid.UUID, f func(feed *database.Feed)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.feeds {
+		if m.feeds[i].ID == id {
+			f(&m.feeds[i])
+		}
+	}
+}
+
+func (m *memStore) UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error {
+	m.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.Link, feed.Description = arg.Link, arg.Description
+	})
+	return nil
+}
+
+func (m *memStore) MarkFeedGone(ctx context.Context, id uuid.UUID) error {
+	m.updateFeed(id, func(feed *database.Feed) {
+		feed.GoneAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	})
+	return nil
+}
+
+func (m *memStore) RecordFeedRedirect(ctx context.Context, arg database.RecordFeedRedirectParams) (int32, error) {
+	count := int32(-1)
+	m.updateFeed(arg.ID, func(feed *database.Feed) {
+		if feed.MovedTo == arg.MovedTo {
+			feed.MovedCount++
+		} else {
+			feed.MovedCount = 1
+		}
+		feed.MovedTo, count = arg.MovedTo, feed.MovedCount
+	})
+	if count < 0 {
+		return 0, sql.ErrNoRows
+	}
+	return count, nil
+}
+
+func (m *memStore) ClearFeedRedirect(ctx context.Context, id uuid.UUID) error {
+	m.updateFeed(id, func(feed *database.Feed) {
+		feed.MovedTo, feed.MovedCount = sql.NullString{}, 0
+	})
+	return nil
+}
+
+func (m *memStore) SetFeedURL(ctx context.Context, arg database.SetFeedURLParams) error {
+	m.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.Url, feed.MovedTo, feed.MovedCount = arg.Url, sql.NullString{}, 0
+	})
+	return nil
+}
+
+// MergeFeed hands the followers and posts of one feed over to another, and
+// its full-content setting if it is on.
+func (m *memStore) MergeFeed(ctx context.Context, arg database.MergeFeedParams) error {
+	fullContent := false
+	m.updateFeed(arg.FromID, func(feed *database.Feed) { fullContent = feed.FetchFullContent })
+	if fullContent {
+		m.updateFeed(arg.IntoID, func(feed *database.Feed) { feed.FetchFullContent = true })
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, follows := range m.follows {
+		if follows[arg.FromID] {
+			follows[arg.IntoID] = true
+		}
+	}
+	for i := range m.posts {
+		if m.posts[i].FeedID == arg.FromID {
+			m.posts[i].FeedID = arg.IntoID
+		}
+	}
+	return nil
+}
+
+func (m *memStore) MoveWebSubSubscription(ctx context.Context, arg database.MoveWebSubSubscriptionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub := m.subs[arg.FromID]
+	if sub == nil || m.subs[arg.IntoID] != nil {
+		return nil
+	}
+	delete(m.subs, arg.FromID)
+	sub.FeedID, sub.State, sub.RequestedAt, sub.LeaseExpiresAt = arg.IntoID, "new", sql.NullTime{}, sql.NullTime{}
+	m.subs[arg.IntoID] = sub
+	return nil
+}
+
+// DeleteFeed deletes a feed with its follows, posts and subscription, as
+// the foreign keys cascade.
+func (m *memStore) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.feeds = slices.DeleteFunc(m.feeds, func(feed database.Feed) bool { return feed.ID == id })
+	m.posts = slices.DeleteFunc(m.posts, func(post database.Post) bool { return post.FeedID == id })
+	for _, follows := range m.follows {
+		delete(follows, id)
+	}
+	delete(m.subs, id)
+	return nil
+}
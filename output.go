@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats accepted by the --output option.
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+	outputTSV   = "tsv"
+	outputTable = "table"
+)
+
+var outputFormats = []string{outputText, outputJSON, outputCSV, outputTSV, outputTable}
+
+// listing is what a listing command (users, feeds, following, browse) has to
+// print. Records is marshalled for --output json and must use stable field
+// names; Columns and Rows back csv, tsv and table. Rows hold full IDs so
+// scripts can feed them back to gator; the IDColumns are shortened for the
+// table, which is for people.
+type listing struct {
+	Columns   []string
+	IDColumns []string
+	Rows      [][]string
+	Records   any
+}
+
+// outputValue is the --output flag; it only accepts one of outputFormats.
+type outputValue string
+
+func (v *outputValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return string(*v)
+}
+
+func (v *outputValue) Set(s string) error {
+	for _, f := range outputFormats {
+		if f == s {
+			*v = outputValue(s)
+			return nil
+		}
+	}
+	return fmt.Errorf("use %s", strings.Join(outputFormats, "|"))
+}
+
+func (v *outputValue) Get() any {
+	return string(*v)
+}
+
+// outputFlags defines --output and its -o shorthand, writing into format.
+func outputFlags(fs *flag.FlagSet, format *string) {
+	usage := "output format: " + strings.Join(outputFormats, "|")
+	fs.Var((*outputValue)(format), "output", usage)
+	fs.Var((*outputValue)(format), "o", "shorthand for --output")
+}
+
+// parseGlobalFlags reads the flags given before the command name, e.g.
+// `gator --output json feeds`. Parsing stops at the first argument that
+// isn't a flag, or after "--"; the rest is returned untouched.
+func parseGlobalFlags(args []string) (string, []string, error) {
+	format := outputText
+	fs := flag.NewFlagSet("gator", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	outputFlags(fs, &format)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return format, []string{"help"}, nil
+		}
+		return "", nil, usageError("%v", err)
+	}
+	return format, fs.Args(), nil
+}
+
+// printListing writes l to stdout in the state's output format. text prints
+// the human-readable form used by --output text.
+func (s *state) printListing(l listing, text func()) error {
+	return writeListing(os.Stdout, s.output, l, text)
+}
+
+func writeListing(w io.Writer, format string, l listing, text func()) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(l.Records)
+	case outputCSV, outputTSV:
+		cw := csv.NewWriter(w)
+		if format == outputTSV {
+			cw.Comma = '\t'
+		}
+		cw.Write(l.Columns)
+		cw.WriteAll(l.Rows)
+		return cw.Error()
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(l.Columns, "\t")))
+		short := map[int]bool{}
+		for i, column := range l.Columns {
+			short[i] = slices.Contains(l.IDColumns, column)
+		}
+		for _, row := range l.Rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				if id, err := uuid.Parse(cell); err == nil && short[i] {
+					cell = shortID(id)
+				}
+				cells[i] = cell
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		return tw.Flush()
+	default:
+		text()
+		return nil
+	}
+}
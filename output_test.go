@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantFormat string
+		wantRest   []string
+		wantErr    bool
+	}{
+		{[]string{"feeds"}, outputText, []string{"feeds"}, false},
+		{[]string{"--output", "json", "feeds"}, outputJSON, []string{"feeds"}, false},
+		{[]string{"--output=csv", "feeds"}, outputCSV, []string{"feeds"}, false},
+		{[]string{"-o", "table", "feeds"}, outputTable, []string{"feeds"}, false},
+		// Only flags before the command are global.
+		{[]string{"posts", "-o", "json"}, outputText, []string{"posts", "-o", "json"}, false},
+		{[]string{"--", "-o", "json"}, outputText, []string{"-o", "json"}, false},
+		{[]string{"-o", "xml", "feeds"}, "", nil, true},
+		{[]string{"-o"}, "", nil, true},
+		{[]string{"--verbose", "feeds"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		format, rest, err := parseGlobalFlags(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseGlobalFlags(%q) error = %v, want error %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if exitCode(err) != exitOK && exitCode(err) != exitUsage {
+			t.Errorf("parseGlobalFlags(%q) exit code %d", tt.args, exitCode(err))
+		}
+		if format != tt.wantFormat || !reflect.DeepEqual(rest, tt.wantRest) {
+			t.Errorf("parseGlobalFlags(%q) = %q, %q; want %q, %q", tt.args, format, rest, tt.wantFormat, tt.wantRest)
+		}
+	}
+}
+
+func TestCommandOutputFlag(t *testing.T) {
+	var gotArgs []string
+	var gotFormat string
+	c := &commands{}
+	handler := func(s *state, cmd command) error {
+		gotArgs, gotFormat = cmd.args, s.output
+		return nil
+	}
+	c.register(&commandSpec{name: "list", maxArgs: -1, listing: true, handler: handler})
+	c.register(&commandSpec{name: "say", maxArgs: -1, handler: handler})
+
+	tests := []struct {
+		args       []string
+		wantFormat string
+		wantArgs   []string
+		wantErr    bool
+	}{
+		{[]string{"list"}, outputText, nil, false},
+		{[]string{"list", "-o", "json"}, outputJSON, nil, false},
+		{[]string{"list", "a", "--output=tsv", "b"}, outputTSV, []string{"a", "b"}, false},
+		{[]string{"list", "--", "-o", "json"}, outputText, []string{"-o", "json"}, false},
+		{[]string{"list", "-o", "yaml"}, "", nil, true},
+		// Commands that don't list take "-o" as an ordinary argument.
+		{[]string{"say", "-o", "json"}, outputText, []string{"-o", "json"}, false},
+	}
+	for _, tt := range tests {
+		gotArgs, gotFormat = nil, ""
+		s := &state{output: outputText}
+		err := c.run(s, tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("run(%q) error = %v, want error %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			if exitCode(err) != exitUsage {
+				t.Errorf("run(%q) exit code %d, want %d", tt.args, exitCode(err), exitUsage)
+			}
+			continue
+		}
+		if gotFormat != tt.wantFormat || !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+			t.Errorf("run(%q) = %q, %q; want %q, %q", tt.args, gotFormat, gotArgs, tt.wantFormat, tt.wantArgs)
+		}
+	}
+}
+
+func TestWriteListing(t *testing.T) {
+	l := listing{
+		Columns: []string{"id", "name"},
+		Rows:    [][]string{{"1", "Go, Blog"}, {"2", "Rust"}},
+		Records: []map[string]string{{"id": "1", "name": "Go, Blog"}},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{outputJSON, "[\n  {\n    \"id\": \"1\",\n    \"name\": \"Go, Blog\"\n  }\n]\n"},
+		{outputCSV, "id,name\n1,\"Go, Blog\"\n2,Rust\n"},
+		{outputTSV, "id\tname\n1\tGo, Blog\n2\tRust\n"},
+		{outputTable, "ID  NAME\n1   Go, Blog\n2   Rust\n"},
+		{outputText, "text\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeListing(&buf, tt.format, l, func() { buf.WriteString("text\n") }); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestWriteListingIDs(t *testing.T) {
+	const id = "0b5c8e2a-7d0f-4f39-9c1e-6a2b3c4d5e6f"
+	l := listing{
+		Columns:   []string{"id", "name", "note"},
+		IDColumns: []string{"id"},
+		Rows:      [][]string{{id, "Go", id}},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		// Machine-readable rows keep the full ID so it can be passed back.
+		{outputCSV, "id,name,note\n" + id + ",Go," + id + "\n"},
+		{outputTSV, "id\tname\tnote\n" + id + "\tGo\t" + id + "\n"},
+		{outputTable, "ID        NAME  NOTE\n0b5c8e2a  Go    " + id + "\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeListing(&buf, tt.format, l, nil); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+var errWrongPassword = errors.New("incorrect password")
+
+// stdin is shared so that several prompts in a row work when input is piped.
+var stdin = bufio.NewReader(os.Stdin)
+
+// promptPassword reads a line from stdin, without echo when it is a terminal.
+func promptPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		pw, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(pw), err
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptNewPassword asks for a password twice and returns its hash. An empty
+// password means "no password" and yields an invalid NullString; so does no
+// input at all, so scripts can run e.g. "gator register alice </dev/null".
+func promptNewPassword(prompt string) (sql.NullString, error) {
+	pw, err := promptPassword(prompt)
+	if errors.Is(err, io.EOF) {
+		fmt.Println()
+		return sql.NullString{}, nil
+	}
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if pw == "" {
+		return sql.NullString{}, nil
+	}
+	confirm, err := promptPassword("Confirm password: ")
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if pw != confirm {
+		return sql.NullString{}, fmt.Errorf("passwords do not match")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+// verifyUserPassword prompts for the user's password if they have one set.
+// Users without a password are let through unchallenged.
+func verifyUserPassword(user database.User) error {
+	if !user.PasswordHash.Valid {
+		return nil
+	}
+	pw, err := promptPassword(fmt.Sprintf("Password for %s: ", user.Name))
+	if err != nil {
+		return err
+	}
+	return checkPassword(user, pw)
+}
+
+// checkPassword compares pw against the user's stored hash. Users without a
+// password never match.
+func checkPassword(user database.User, pw string) error {
+	if !user.PasswordHash.Valid {
+		return errWrongPassword
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(pw)) != nil {
+		return errWrongPassword
+	}
+	return nil
+}
+
+func handlerPasswd(s *state, cmd command, user database.User) error {
+	if err := verifyUserPassword(user); err != nil {
+		return fmt.Errorf("failed to verify current password: %w", err)
+	}
+	hash, err := promptNewPassword("New password (leave empty to remove): ")
+	if err != nil {
+		return fmt.Errorf("failed to read new password: %w", err)
+	}
+
+	ctx := context.Background()
+	err = s.db.SetUserPassword(ctx, database.SetUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: hash,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+	if hash.Valid {
+		fmt.Printf("Password updated for %s\n", user.Name)
+	} else {
+		fmt.Printf("Password removed for %s\n", user.Name)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestPromptNewPasswordPiped(t *testing.T) {
+	defer func(r *bufio.Reader) { stdin = r }(stdin)
+	tests := []struct {
+		name    string
+		input   string
+		want    bool // a password is set
+		wantErr bool
+	}{
+		{"no input", "", false, false},
+		{"empty line", "\n", false, false},
+		{"password twice", "hunter2\nhunter2\n", true, false},
+		{"without final newline", "hunter2\nhunter2", true, false},
+		{"mismatch", "hunter2\nhunter3\n", false, true},
+		{"no confirmation", "hunter2\n", false, true},
+	}
+	for _, tt := range tests {
+		stdin = bufio.NewReader(strings.NewReader(tt.input))
+		hash, err := promptNewPassword("Password: ")
+		if (err != nil) != tt.wantErr || hash.Valid != tt.want {
+			t.Errorf("%s: hash set %v, error %v", tt.name, hash.Valid, err)
+		}
+	}
+}
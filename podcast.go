@@ -0,0 +1,444 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseItunesDuration reads itunes:duration, which is either a number of
+// seconds or [[HH:]MM:]SS. Anything unparseable counts as unknown (0).
+func parseItunesDuration(s string) int32 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	var total float64
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return int32(total)
+}
+
+func formatDuration(seconds int32) string {
+	if seconds <= 0 {
+		return ""
+	}
+	h, m, sec := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
+
+// getEpisodes lists the user's podcast episodes, newest first within each
+// feed, keeping only the first media enclosure of every post. feedRef, if
+// set, restricts the list to one followed feed.
+func getEpisodes(ctx context.Context, s *state, user database.User, feedRef string) ([]database.GetEpisodesForUserRow, error) {
+	var feedSeq int64
+	if feedRef != "" {
+		feeds, err := s.db.GetFeedsForUser(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get followed feeds: %w", err)
+		}
+		feed, err := resolveFeed(feeds, feedRef)
+		if err != nil {
+			return nil, err
+		}
+		feedSeq = feed.Seq
+	}
+	rows, err := s.db.GetEpisodesForUser(ctx, database.GetEpisodesForUserParams{
+		UserID:  user.ID,
+		FeedSeq: feedSeq,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get episodes: %w", err)
+	}
+	seen := map[uuid.UUID]bool{}
+	episodes := rows[:0]
+	for _, row := range rows {
+		if !seen[row.PostID] {
+			seen[row.PostID] = true
+			episodes = append(episodes, row)
+		}
+	}
+	return episodes, nil
+}
+
+func podcastListFlags(fs *flag.FlagSet) {
+	fs.String("feed", "", "only list episodes of this feed")
+}
+
+func handlerPodcastList(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	episodes, err := getEpisodes(ctx, s, user, cmd.stringFlag("feed"))
+	if err != nil {
+		return err
+	}
+
+	type episodeRecord struct {
+		ID         uuid.UUID `json:"id"`
+		Feed       string    `json:"feed"`
+		Title      string    `json:"title"`
+		Episode    *int32    `json:"episode"`
+		Duration   int32     `json:"duration_seconds"`
+		URL        string    `json:"url"`
+		Downloaded string    `json:"downloaded_to"`
+		Played     bool      `json:"played"`
+	}
+	out := listing{Columns: []string{"id", "feed", "title", "episode", "duration", "url", "downloaded_to", "played"},
+		IDColumns: []string{"id"}}
+	records := make([]episodeRecord, 0, len(episodes))
+	for _, e := range episodes {
+		record := episodeRecord{
+			ID:       e.PostID,
+			Feed:     e.FeedName,
+			Title:    e.Title,
+			Duration: e.Duration,
+			URL:      e.Url,
+			Played:   e.PlayedAt.Valid,
+		}
+		episode := ""
+		if e.Episode.Valid {
+			record.Episode = &e.Episode.Int32
+			episode = strconv.Itoa(int(e.Episode.Int32))
+		}
+		if e.DownloadedAt.Valid {
+			record.Downloaded = e.FilePath.String
+		}
+		records = append(records, record)
+		out.Rows = append(out.Rows, []string{e.PostID.String(), e.FeedName, e.Title, episode,
+			formatDuration(e.Duration), e.Url, record.Downloaded, strconv.FormatBool(record.Played)})
+	}
+	out.Records = records
+
+	return s.printListing(out, func() {
+		if len(episodes) == 0 {
+			fmt.Println("No episodes - follow a podcast feed and run agg first.")
+			return
+		}
+		var feed uuid.UUID
+		for _, e := range episodes {
+			if e.FeedID != feed {
+				feed = e.FeedID
+				fmt.Printf("\n%s\n", e.FeedName)
+			}
+			var details []string
+			if e.Episode.Valid {
+				details = append(details, fmt.Sprintf("#%d", e.Episode.Int32))
+			}
+			if d := formatDuration(e.Duration); d != "" {
+				details = append(details, d)
+			}
+			if e.DownloadedAt.Valid {
+				details = append(details, "downloaded")
+			}
+			if e.PlayedAt.Valid {
+				details = append(details, "played")
+			}
+			line := fmt.Sprintf("  [%s] %s", shortID(e.PostID), e.Title)
+			if len(details) > 0 {
+				line += " (" + strings.Join(details, ", ") + ")"
+			}
+			fmt.Println(line)
+		}
+	})
+}
+
+func podcastDownloadFlags(fs *flag.FlagSet) {
+	fs.String("feed", "", "only download episodes of this feed")
+	fs.Int("latest", 1, "consider the N newest episodes of each feed")
+	fs.String("dir", "", "directory to save episodes in (required)")
+}
+
+func handlerPodcastDownload(s *state, cmd command, user database.User) error {
+	dir, latest := cmd.stringFlag("dir"), cmd.intFlag("latest")
+	if dir == "" {
+		return usageError("--dir is required")
+	}
+	if latest <= 0 {
+		return usageError("--latest must be positive")
+	}
+
+	// Interrupting leaves the .part file behind for the next run to resume.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	episodes, err := getEpisodes(ctx, s, user, cmd.stringFlag("feed"))
+	if err != nil {
+		return err
+	}
+	fileNames := episodeFileNames(episodes)
+	headers := map[uuid.UUID]http.Header{}
+	perFeed := map[uuid.UUID]int{}
+	downloaded, failed := 0, 0
+	for _, e := range episodes {
+		perFeed[e.FeedID]++
+		if perFeed[e.FeedID] > latest || e.DownloadedAt.Valid || e.PlayedAt.Valid {
+			continue
+		}
+
+		feedDir := filepath.Join(dir, sanitizeFileName(e.FeedName))
+		if err := os.MkdirAll(feedDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", feedDir, err)
+		}
+		header, ok := headers[e.FeedID]
+		if !ok {
+			if header, err = feedRequestHeader(ctx, s, e.FeedID); err != nil {
+				return fmt.Errorf("failed to load credentials for %s: %w", e.FeedName, err)
+			}
+			headers[e.FeedID] = header
+		}
+		dest := filepath.Join(feedDir, fileNames[e.PostID])
+		fmt.Printf("Downloading %s - %s\n", e.FeedName, e.Title)
+		size, err := downloadFile(ctx, s.http, e.Url, dest, sameHostHeader(header, e.FeedUrl, e.Url))
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return fmt.Errorf("download interrupted - run the command again to resume")
+		}
+		if err != nil {
+			fmt.Printf("  Failed: %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("  Saved %s (%s)\n", dest, formatSize(size))
+		err = s.db.MarkEpisodeDownloaded(ctx, database.MarkEpisodeDownloadedParams{
+			UserID:   user.ID,
+			PostID:   e.PostID,
+			FilePath: sql.NullString{String: dest, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record download: %w", err)
+		}
+		downloaded++
+	}
+
+	fmt.Printf("%d episode(s) downloaded\n", downloaded)
+	if failed > 0 {
+		return fmt.Errorf("%d episode(s) failed to download", failed)
+	}
+	return nil
+}
+
+func handlerPodcastPlayed(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	post, err := resolvePost(ctx, s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	err = s.db.MarkEpisodePlayed(ctx, database.MarkEpisodePlayedParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark episode played: %w", err)
+	}
+	fmt.Printf("Marked %s as played\n", post.Title)
+	return nil
+}
+
+// errBadResume means a server answered a range request with content that
+// doesn't continue the partial file.
+var errBadResume = errors.New("server did not resume where the partial download ends")
+
+// downloadFile saves rawURL to dest by way of dest + ".part", resuming a
+// previous partial download when the server supports range requests. It
+// returns the final size of the file. header is sent along, as with fetch.
+func downloadFile(ctx context.Context, client *httpClient, rawURL, dest string, header http.Header) (int64, error) {
+	size, err := downloadPart(ctx, client, rawURL, dest, header)
+	if errors.Is(err, errBadResume) {
+		fmt.Printf("  %v - starting over\n", err)
+		if err := os.Remove(dest + ".part"); err != nil {
+			return 0, err
+		}
+		size, err = downloadPart(ctx, client, rawURL, dest, header)
+	}
+	return size, err
+}
+
+func downloadPart(ctx context.Context, client *httpClient, rawURL, dest string, header http.Header) (int64, error) {
+	part := dest + ".part"
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := newGetRequest(ctx, rawURL, header)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	res, err := client.send(client.download, req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		contentRange := res.Header.Get("Content-Range")
+		if start, _, ok := parseContentRange(contentRange); !ok || start != offset {
+			return 0, fmt.Errorf("%w (have %d bytes, got Content-Range %q)", errBadResume, offset, contentRange)
+		}
+		fmt.Printf("  Resuming at %s\n", formatSize(offset))
+	case http.StatusOK:
+		// No range support (or a fresh start): begin again from zero.
+		if err := f.Truncate(0); err != nil {
+			return 0, err
+		}
+		if offset, err = f.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete, unless the server says the
+		// file is some other size.
+		contentRange := res.Header.Get("Content-Range")
+		if _, total, ok := parseContentRange(contentRange); offset == 0 || (ok && total >= 0 && total != offset) {
+			return 0, fmt.Errorf("%w (have %d bytes, got Content-Range %q)", errBadResume, offset, contentRange)
+		}
+	default:
+		return 0, &httpStatusError{URL: rawURL, StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	if res.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		n, err := io.Copy(f, res.Body)
+		offset += n
+		if err != nil {
+			return offset, err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return offset, err
+	}
+	return offset, os.Rename(part, dest)
+}
+
+// parseContentRange reads a Content-Range of the form "bytes 100-199/1000"
+// or "bytes */1000". start is -1 in the second form and total is -1 when
+// the size is "*".
+func parseContentRange(s string) (start, total int64, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(s), "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	span, size, found := strings.Cut(rest, "/")
+	if !found {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		var err error
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil || total < 0 {
+			return 0, 0, false
+		}
+	}
+	if span == "*" {
+		return -1, total, true
+	}
+	first, last, found := strings.Cut(span, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	if end, err := strconv.ParseInt(last, 10, 64); err != nil || end < start {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// episodeFileNames names the file of every episode. Episodes that would
+// share a file in the same directory - compared case-insensitively, for
+// filesystems that fold case - all get their short ID appended, so that
+// the names don't depend on which episodes are downloaded first.
+func episodeFileNames(episodes []database.GetEpisodesForUserRow) map[uuid.UUID]string {
+	key := func(e database.GetEpisodesForUserRow) string {
+		return strings.ToLower(sanitizeFileName(e.FeedName) + "/" + episodeFileName(e, false))
+	}
+	count := map[string]int{}
+	for _, e := range episodes {
+		count[key(e)]++
+	}
+	names := make(map[uuid.UUID]string, len(episodes))
+	for _, e := range episodes {
+		names[e.PostID] = episodeFileName(e, count[key(e)] > 1)
+	}
+	return names
+}
+
+// episodeFileName is "<date> <title>.<ext>", prefixed with the episode
+// number when the feed provides one and followed by " [<short id>]" when
+// withID is set.
+func episodeFileName(e database.GetEpisodesForUserRow, withID bool) string {
+	date := e.CreatedAt
+	if e.PublishedAt.Valid {
+		date = e.PublishedAt.Time
+	}
+	name := date.Format(time.DateOnly) + " "
+	if e.Episode.Valid {
+		name += fmt.Sprintf("%03d ", e.Episode.Int32)
+	}
+	name += e.Title
+
+	ext := ""
+	if u, err := url.Parse(e.Url); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	if len(ext) < 2 || len(ext) > 5 {
+		ext = ""
+		if exts, _ := mime.ExtensionsByType(e.MimeType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	name = sanitizeFileName(name)
+	if withID {
+		name += " [" + shortID(e.PostID) + "]"
+	}
+	return name + ext
+}
+
+// sanitizeFileName makes s safe to use as a single path element on any
+// common filesystem.
+func sanitizeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, s)
+	s = strings.Trim(strings.TrimSpace(s), ".")
+	if r := []rune(s); len(r) > 120 {
+		s = string(r[:120])
+	}
+	if s == "" {
+		s = "untitled"
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gator/internal/config"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		in          string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 100-199/1000", 100, 1000, true},
+		{"bytes 0-0/1", 0, 1, true},
+		{"bytes 100-199/*", 100, -1, true},
+		{"bytes */1000", -1, 1000, true},
+		{"bytes 200-100/1000", 0, 0, false},
+		{"bytes 100-/1000", 0, 0, false},
+		{"bytes 100-199", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, size, ok := parseContentRange(tt.in)
+		if ok != tt.ok || (ok && (start != tt.start || size != tt.size)) {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v", tt.in, start, size, ok, tt.start, tt.size, tt.ok)
+		}
+	}
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	episode := bytes.Repeat([]byte("0123456789"), 1000)
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "episode.mp3", time.Time{}, bytes.NewReader(episode))
+	}
+	tests := []struct {
+		name    string
+		partial []byte
+		handler http.HandlerFunc
+	}{
+		{name: "fresh download", handler: serve},
+		{name: "resume", partial: episode[:4321], handler: serve},
+		{name: "already complete", partial: episode, handler: serve},
+		{name: "no range support", partial: episode[:4321], handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write(episode)
+		}},
+		{name: "resumes from the wrong offset", partial: episode[:4321], handler: func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") == "" {
+				w.Write(episode)
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 1000-%d/%d", len(episode)-1, len(episode)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(episode[1000:])
+		}},
+		{name: "206 without Content-Range", partial: episode[:4321], handler: func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "" {
+				w.WriteHeader(http.StatusPartialContent)
+			}
+			w.Write(episode)
+		}},
+		{name: "partial file longer than the episode", partial: append(bytes.Clone(episode), "junk"...), handler: serve},
+	}
+	client := newTestHTTPClient(t, config.HTTPConfig{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			dest := filepath.Join(t.TempDir(), "episode.mp3")
+			if tt.partial != nil {
+				if err := os.WriteFile(dest+".part", tt.partial, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			size, err := downloadFile(context.Background(), client, srv.URL+"/episode.mp3", dest, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != int64(len(episode)) || !bytes.Equal(got, episode) {
+				t.Errorf("downloaded %d bytes (size %d), want the %d-byte episode", len(got), size, len(episode))
+			}
+			if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+				t.Errorf(".part file left behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestEpisodeFileNames(t *testing.T) {
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	episode := func(feed, title, url string) database.GetEpisodesForUserRow {
+		return database.GetEpisodesForUserRow{PostID: uuid.New(), FeedName: feed, Title: title, Url: url,
+			CreatedAt: day, MimeType: "audio/mpeg"}
+	}
+	episodes := []database.GetEpisodesForUserRow{
+		episode("Show", "News: today", "https://example.com/a.mp3"),
+		episode("Show", "News/ today", "https://example.com/b.mp3"),
+		episode("Show", "news: TODAY", "https://example.com/c.mp3"),
+		episode("Show", "Interview", "https://example.com/d.mp3"),
+		episode("Other show", "News: today", "https://example.com/e.mp3"),
+	}
+	names := episodeFileNames(episodes)
+
+	seen := map[string]bool{}
+	for _, e := range episodes {
+		key := strings.ToLower(sanitizeFileName(e.FeedName) + "/" + names[e.PostID])
+		if seen[key] {
+			t.Errorf("%q: file name %q used twice", e.Title, names[e.PostID])
+		}
+		seen[key] = true
+	}
+	for i, want := range []string{
+		"2024-05-01 News- today [" + shortID(episodes[0].PostID) + "].mp3",
+		"2024-05-01 News- today [" + shortID(episodes[1].PostID) + "].mp3",
+		"2024-05-01 news- TODAY [" + shortID(episodes[2].PostID) + "].mp3",
+		"2024-05-01 Interview.mp3",
+		"2024-05-01 News- today.mp3",
+	} {
+		if got := names[episodes[i].PostID]; got != want {
+			t.Errorf("episode %d: file name %q, want %q", i, got, want)
+		}
+	}
+	// Names don't depend on the order episodes are listed in.
+	reversed := []database.GetEpisodesForUserRow{episodes[4], episodes[3], episodes[2], episodes[1], episodes[0]}
+	for id, name := range episodeFileNames(reversed) {
+		if names[id] != name {
+			t.Errorf("file name changed with the order: %q, was %q", name, names[id])
+		}
+	}
+}
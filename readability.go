@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// minArticleText is the least amount of text an extraction must yield to be
+// kept; anything shorter is most likely navigation or a paywall stub.
+const minArticleText = 200
+
+// The class/id patterns follow Mozilla's Readability.
+var (
+	unlikelyCandidate = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote`)
+	maybeCandidate    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveHint      = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeHint      = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// fetchArticle downloads the page at articleURL, with any extra request
+// header, and returns its main content as cleaned-up HTML, or "" if nothing
+// article-like was found.
+func fetchArticle(ctx context.Context, client *httpClient, articleURL string, header http.Header) (string, error) {
+	body, res, err := client.fetch(ctx, articleURL, "text/html,application/xhtml+xml", header)
+	if err != nil {
+		return "", err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType != "" &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", fmt.Errorf("not an HTML page: %s", mediaType)
+	}
+	base := res.Request.URL
+	return extractArticle(body, base)
+}
+
+// extractArticle finds the element holding the page's main text using a
+// Readability-style scoring of paragraphs and their ancestors. Links and
+// images in the result are made absolute against base.
+func extractArticle(page []byte, base *url.URL) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+	prune(doc)
+
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode || n.DataAtom == atom.Html {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = baseScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+	walkElements(doc, func(n *html.Node) {
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td:
+		case atom.Div:
+			if hasBlockChild(n) {
+				return
+			}
+		default:
+			return
+		}
+		text := innerText(n)
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+	})
+
+	var top *html.Node
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if top == nil || scores[n] > scores[top] {
+			top = n
+		}
+	}
+	if top == nil {
+		return "", nil
+	}
+
+	// Siblings that score well, or are substantial paragraphs, are usually
+	// parts of the same article split across containers.
+	parts := []*html.Node{top}
+	if top.Parent != nil {
+		parts = parts[:0]
+		threshold := math.Max(10, scores[top]*0.2)
+		for sib := top.Parent.FirstChild; sib != nil; sib = sib.NextSibling {
+			if sib.Type != html.ElementNode {
+				continue
+			}
+			score, scored := scores[sib]
+			switch {
+			case sib == top, scored && score >= threshold:
+			case sib.DataAtom == atom.P && len(innerText(sib)) > 80 && linkDensity(sib) < 0.25:
+			default:
+				continue
+			}
+			parts = append(parts, sib)
+		}
+	}
+
+	var buf bytes.Buffer
+	text, linked := 0, 0.0
+	for _, n := range parts {
+		clean(n, base)
+		length := len(innerText(n))
+		text += length
+		linked += linkDensity(n) * float64(length)
+		if err := html.Render(&buf, n); err != nil {
+			return "", err
+		}
+	}
+	// A page that is mostly links is an index or archive, not an article.
+	if text < minArticleText || linked > float64(text)/2 {
+		return "", nil
+	}
+	return buf.String(), nil
+}
+
+// prune removes elements that never hold article text.
+func prune(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		} else if c.Type == html.ElementNode {
+			switch c.DataAtom {
+			case atom.Script, atom.Style, atom.Noscript, atom.Iframe, atom.Form, atom.Nav,
+				atom.Aside, atom.Footer, atom.Button, atom.Svg, atom.Input, atom.Select,
+				atom.Textarea, atom.Link, atom.Meta, atom.Object, atom.Embed:
+				n.RemoveChild(c)
+			case atom.Html, atom.Body, atom.Article, atom.Main:
+				prune(c)
+			default:
+				hint := attr(c, "class") + " " + attr(c, "id")
+				if unlikelyCandidate.MatchString(hint) && !maybeCandidate.MatchString(hint) {
+					n.RemoveChild(c)
+				} else {
+					prune(c)
+				}
+			}
+		}
+		c = next
+	}
+}
+
+// clean strips presentation from an extracted subtree: attributes other
+// than links and image sources, and link-heavy or negatively hinted blocks.
+func clean(n *html.Node, base *url.URL) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode {
+			switch c.DataAtom {
+			case atom.Div, atom.Section, atom.Ul, atom.Ol, atom.Table:
+				text := innerText(c)
+				hasImage := findElement(c, atom.Img) != nil
+				if classWeight(c) < 0 || (linkDensity(c) > 0.5 && len(text) < 200) || (len(text) < 25 && !hasImage) {
+					n.RemoveChild(c)
+					c = next
+					continue
+				}
+			}
+			clean(c, base)
+		}
+		c = next
+	}
+	if n.Type != html.ElementNode {
+		return
+	}
+	var keep []html.Attribute
+	for _, a := range n.Attr {
+		switch {
+		case n.DataAtom == atom.Img && a.Key == "alt":
+			keep = append(keep, html.Attribute{Key: a.Key, Val: a.Val})
+		case n.DataAtom == atom.A && a.Key == "href",
+			n.DataAtom == atom.Img && a.Key == "src":
+			// javascript:, data: and the like have no place in a stored
+			// article; links that aren't http(s) are dropped.
+			if u, err := base.Parse(strings.TrimSpace(a.Val)); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+				keep = append(keep, html.Attribute{Key: a.Key, Val: u.String()})
+			}
+		}
+	}
+	n.Attr = keep
+}
+
+func baseScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, hint := range []string{attr(n, "class"), attr(n, "id")} {
+		if hint == "" {
+			continue
+		}
+		if negativeHint.MatchString(hint) {
+			weight -= 25
+		}
+		if positiveHint.MatchString(hint) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+func hasBlockChild(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.DataAtom {
+		case atom.A, atom.Blockquote, atom.Dl, atom.Div, atom.Img, atom.Ol, atom.P,
+			atom.Pre, atom.Table, atom.Ul, atom.Section, atom.Article, atom.Figure:
+			return true
+		}
+	}
+	return false
+}
+
+func innerText(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func linkDensity(n *html.Node) float64 {
+	total := len(innerText(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	walkElements(n, func(c *html.Node) {
+		if c.DataAtom == atom.A {
+			linked += len(innerText(c))
+		}
+	})
+	return float64(linked) / float64(total)
+}
+
+// walkElements calls f for every element below n, outermost first. Links
+// are not descended into.
+func walkElements(n *html.Node, f func(*html.Node)) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		f(c)
+		if c.DataAtom != atom.A {
+			walkElements(c, f)
+		}
+	}
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walkElements(n, func(c *html.Node) {
+		if found == nil && c.DataAtom == a {
+			found = c
+		}
+	})
+	return found
+}
+
+// storeFullContent extracts the article behind a newly stored post, fetched
+// with header (see sameHostHeader). Failures are only reported: the post
+// keeps its description.
+func storeFullContent(ctx context.Context, s *state, postID uuid.UUID, articleURL string, header http.Header) {
+	content, err := fetchArticle(ctx, s.http, articleURL, header)
+	if err != nil {
+		fmt.Printf("   Failed to fetch full content: %+v\n", err)
+		return
+	}
+	if content == "" {
+		return
+	}
+	err = s.db.SetPostContent(ctx, database.SetPostContentParams{
+		ID:      postID,
+		Content: sql.NullString{String: content, Valid: true},
+	})
+	if err != nil {
+		fmt.Printf("   Failed to store full content: %+v\n", err)
+	}
+}
+
+func handlerFeedFullContent(s *state, cmd command, user database.User) error {
+	var enable bool
+	switch cmd.args[1] {
+	case "on":
+		enable = true
+	case "off":
+	default:
+		return usageError("invalid setting %q (use on or off)", cmd.args[1])
+	}
+	ctx := context.Background()
+	feeds, err := s.db.GetFeedsForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get followed feeds: %w", err)
+	}
+	feed, err := resolveFeed(feeds, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if err := checkFeedCreator(ctx, s, feed, user, "change whether its posts are fetched in full"); err != nil {
+		return err
+	}
+	err = s.db.SetFeedFetchFullContent(ctx, database.SetFeedFetchFullContentParams{
+		ID:               feed.ID,
+		FetchFullContent: enable,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update feed: %w", err)
+	}
+	if enable {
+		fmt.Printf("New posts from %s will be fetched in full\n", feed.Name)
+	} else {
+		fmt.Printf("Posts from %s will keep the feed's own description\n", feed.Name)
+	}
+	return nil
+}
+
+// postHTML is the body to show for a post: the extracted full content when
+// there is any, the feed's own description otherwise.
+func postHTML(description, content sql.NullString) string {
+	if content.Valid && content.String != "" {
+		return content.String
+	}
+	return description.String
+}
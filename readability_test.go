@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"gator/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestHTTPClient is the feed client with the loopback networks httptest
+// servers listen on allowed, and without the politeness delay between
+// requests to one host.
+func newTestHTTPClient(t *testing.T, cfg config.HTTPConfig) *httpClient {
+	t.Helper()
+	cfg.AllowNetworks = append(cfg.AllowNetworks, "127.0.0.0/8", "::1/128")
+	if cfg.HostDelay == "" {
+		cfg.HostDelay = "1ms"
+	}
+	client, err := newHTTPClient(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// newFixtureServer serves the files in testdata as text/html, and anything
+// under /plain/ as text/plain.
+func newFixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, plain := strings.CutPrefix(r.URL.Path, "/plain/")
+		page, err := os.ReadFile(filepath.Join("testdata", filepath.Base(name)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if plain {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		w.Write(page)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchArticle(t *testing.T) {
+	srv := newFixtureServer(t)
+	client := newTestHTTPClient(t, config.HTTPConfig{})
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		notWant []string
+		empty   bool
+		wantErr bool
+	}{
+		{
+			name: "blog post",
+			path: "/posts/article.html",
+			want: []string{
+				"<p>Tracing collectors find live objects",
+				"most objects die young",
+				"every pointer store has to tell the collector",
+				"<a>Share this</a>",
+				`<a href="` + srv.URL + `/posts/write-barriers">the post about write barriers</a>`,
+				`<img src="` + srv.URL + `/posts/images/pauses.png" alt="Pause times"/>`,
+			},
+			notWant: []string{"Popular posts", "Archive", "Great article", "Copyright", "analytics",
+				"class=", "style=", "width=", "JavaScript:", "data:image"},
+		},
+		{
+			name:    "article split across siblings",
+			path:    "/split.html",
+			want:    []string{"incremental type checker", "minimum supported version", "structured logging"},
+			notWant: []string{"Download"},
+		},
+		{name: "list of links", path: "/links.html", empty: true},
+		{name: "paywall stub", path: "/paywall.html", empty: true},
+		{name: "not HTML", path: "/plain/article.html", wantErr: true},
+		{name: "missing page", path: "/nope.html", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetchArticle(context.Background(), client, srv.URL+tt.path, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("no error, extracted %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.empty {
+				if got != "" {
+					t.Errorf("extraction should fail, got %q", got)
+				}
+				return
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("article lacks %q:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("article contains %q:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreFullContent(t *testing.T) {
+	srv := newFixtureServer(t)
+	db := newMemStore()
+	user := db.addUser("alice")
+	feed := db.addFeed(user, "Example Blog", srv.URL+"/feed.xml")
+	s := newTestState(db)
+	s.http = newTestHTTPClient(t, config.HTTPConfig{})
+
+	article := db.addPost(feed, "Notes on garbage collection", srv.URL+"/article.html")
+	storeFullContent(context.Background(), s, article.ID, article.Url, nil)
+	stored := db.post(article.ID)
+	if !stored.Content.Valid || !strings.Contains(stored.Content.String, "most objects die young") {
+		t.Fatalf("content not stored: %+v", stored.Content)
+	}
+	if got := postHTML(stored.Description, stored.Content); got != stored.Content.String {
+		t.Errorf("postHTML prefers the description over extracted content: %q", got)
+	}
+
+	// When extraction fails the post keeps its description.
+	for _, path := range []string{"/paywall.html", "/plain/article.html", "/nope.html"} {
+		post := db.addPost(feed, "Elsewhere", srv.URL+path)
+		storeFullContent(context.Background(), s, post.ID, post.Url, nil)
+		stored := db.post(post.ID)
+		if stored.Content.Valid {
+			t.Errorf("%s: content stored: %q", path, stored.Content.String)
+		}
+		if got := postHTML(stored.Description, stored.Content); got != "<p>Elsewhere</p>" {
+			t.Errorf("%s: postHTML = %q, want the description", path, got)
+		}
+	}
+}
+
+func TestPostHTML(t *testing.T) {
+	desc := sql.NullString{String: "<p>summary</p>", Valid: true}
+	tests := []struct {
+		description, content sql.NullString
+		want                 string
+	}{
+		{desc, sql.NullString{String: "<p>full</p>", Valid: true}, "<p>full</p>"},
+		{desc, sql.NullString{}, "<p>summary</p>"},
+		{desc, sql.NullString{String: "", Valid: true}, "<p>summary</p>"},
+		{sql.NullString{}, sql.NullString{}, ""},
+	}
+	for _, tt := range tests {
+		if got := postHTML(tt.description, tt.content); got != tt.want {
+			t.Errorf("postHTML(%v, %v) = %q, want %q", tt.description, tt.content, got, tt.want)
+		}
+	}
+}
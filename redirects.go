@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+)
+
+// feedMoveThreshold is how many fetches in a row must permanently redirect
+// to the same URL before the feed is moved there. A single 301 is too easy
+// to get from a misconfigured server or a captive portal.
+const feedMoveThreshold = 3
+
+// trackFeedMove records that fetching feed permanently redirected to movedTo
+// (or, if movedTo is "", that it didn't), and moves the feed once the same
+// redirect has been seen feedMoveThreshold times. If another feed already
+// has the new URL, this one is merged into it, in one transaction: its
+// followers and posts are handed over, as are its credentials, WebSub hub
+// and full-content setting where the other feed has none, and the row is
+// deleted. The feed that new posts belong to is returned.
+func trackFeedMove(ctx context.Context, s *state, feed database.Feed, movedTo string) (database.Feed, error) {
+	if movedTo == "" || movedTo == feed.Url {
+		if !feed.MovedTo.Valid {
+			return feed, nil
+		}
+		return feed, s.db.ClearFeedRedirect(ctx, feed.ID)
+	}
+	count, err := s.db.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{
+		ID:      feed.ID,
+		MovedTo: sql.NullString{String: movedTo, Valid: true},
+	})
+	if err != nil || count < feedMoveThreshold {
+		return feed, err
+	}
+
+	existing, err := s.db.GetFeedByURL(ctx, movedTo)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.db.SetFeedURL(ctx, database.SetFeedURLParams{ID: feed.ID, Url: movedTo})
+		if err != nil {
+			return feed, err
+		}
+		fmt.Printf("Feed %s has moved to %s\n", feed.Url, movedTo)
+		feed.Url = movedTo
+		return feed, nil
+	} else if err != nil {
+		return feed, err
+	}
+
+	err = s.inTx(ctx, func(q database.Querier) error {
+		if err := q.MergeFeed(ctx, database.MergeFeedParams{IntoID: existing.ID, FromID: feed.ID}); err != nil {
+			return err
+		}
+		err := q.MoveFeedCredentials(ctx, database.MoveFeedCredentialsParams{IntoID: existing.ID, FromID: feed.ID})
+		if err != nil {
+			return err
+		}
+		err = q.MoveWebSubSubscription(ctx, database.MoveWebSubSubscriptionParams{IntoID: existing.ID, FromID: feed.ID})
+		if err != nil {
+			return err
+		}
+		return q.DeleteFeed(ctx, feed.ID)
+	})
+	if err != nil {
+		return feed, fmt.Errorf("failed to merge into %s: %w", existing.Name, err)
+	}
+	if feed.FetchFullContent {
+		existing.FetchFullContent = true
+	}
+	fmt.Printf("Feed %s has moved to %s and was merged into %s\n", feed.Url, movedTo, existing.Name)
+	return existing, nil
+}
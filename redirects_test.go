@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"gator/internal/config"
+	"gator/internal/database"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const movingFeedRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Moving</title><link>https://example.com/</link>
+<item><title>Hello</title><link>https://example.com/hello</link></item>
+</channel></rss>`
+
+// newMovingFeedServer serves a feed at /new.xml and, while moved is set,
+// permanently redirects /old.xml there; otherwise /old.xml serves the feed
+// itself. /gone.xml answers 410 Gone.
+func newMovingFeedServer(t *testing.T, moved *atomic.Bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/old.xml" && moved.Load():
+			http.Redirect(w, r, "/new.xml", http.StatusMovedPermanently)
+		case r.URL.Path == "/old.xml" || r.URL.Path == "/new.xml":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(movingFeedRSS))
+		case r.URL.Path == "/gone.xml":
+			http.Error(w, "gone", http.StatusGone)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// scrapeStored fetches the stored feed with ID feed.ID, the way
+// scrapeFeeds does, and returns the feed as stored afterwards.
+func scrapeStored(t *testing.T, s *state, db *memStore, feed database.Feed) database.Feed {
+	t.Helper()
+	ctx := context.Background()
+	stored, err := db.GetFeedByID(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("feed %s: %v", feed.Name, err)
+	}
+	scrapeFeed(ctx, s, stored)
+	stored, _ = db.GetFeedByID(ctx, feed.ID)
+	return stored
+}
+
+func TestFeedMoveThreshold(t *testing.T) {
+	var moved atomic.Bool
+	moved.Store(true)
+	srv := newMovingFeedServer(t, &moved)
+	db := newMemStore()
+	feed := db.addFeed(db.addUser("alice"), "Moving", srv.URL+"/old.xml")
+	s := newTestState(db)
+	s.http = newTestHTTPClient(t, config.HTTPConfig{})
+
+	for i := int32(1); i < feedMoveThreshold; i++ {
+		got := scrapeStored(t, s, db, feed)
+		if got.Url != feed.Url || got.MovedTo.String != srv.URL+"/new.xml" || got.MovedCount != i {
+			t.Fatalf("after redirect %d: url %q, moved to %q x%d; want it still at %q", i, got.Url, got.MovedTo.String, got.MovedCount, feed.Url)
+		}
+	}
+	got := scrapeStored(t, s, db, feed)
+	if got.Url != srv.URL+"/new.xml" {
+		t.Fatalf("after %d redirects the feed is at %q, want it moved", feedMoveThreshold, got.Url)
+	}
+	if got.MovedTo.Valid || got.MovedCount != 0 {
+		t.Errorf("the redirect wasn't cleared after the move: %q x%d", got.MovedTo.String, got.MovedCount)
+	}
+	if posts := len(db.rows(feed.UserID)); posts != 1 {
+		t.Errorf("%d posts stored, want 1", posts)
+	}
+}
+
+func TestFeedMoveResetOnOK(t *testing.T) {
+	var moved atomic.Bool
+	moved.Store(true)
+	srv := newMovingFeedServer(t, &moved)
+	db := newMemStore()
+	feed := db.addFeed(db.addUser("alice"), "Moving", srv.URL+"/old.xml")
+	s := newTestState(db)
+	s.http = newTestHTTPClient(t, config.HTTPConfig{})
+
+	for i := int32(1); i < feedMoveThreshold; i++ {
+		scrapeStored(t, s, db, feed)
+	}
+	moved.Store(false)
+	got := scrapeStored(t, s, db, feed)
+	if got.MovedTo.Valid || got.MovedCount != 0 {
+		t.Fatalf("a 200 didn't reset the redirect count: %q x%d", got.MovedTo.String, got.MovedCount)
+	}
+
+	// The redirects have to be consecutive: counting starts over.
+	moved.Store(true)
+	got = scrapeStored(t, s, db, feed)
+	if got.Url != feed.Url || got.MovedCount != 1 {
+		t.Errorf("after the reset: url %q, count %d; want %q, 1", got.Url, got.MovedCount, feed.Url)
+	}
+}
+
+func TestFeedMoveMerge(t *testing.T) {
+	var moved atomic.Bool
+	moved.Store(true)
+	srv := newMovingFeedServer(t, &moved)
+	db := newMemStore()
+	alice, bob := db.addUser("alice"), db.addUser("bob")
+	old := db.addFeed(alice, "Old", srv.URL+"/old.xml")
+	existing := db.addFeed(bob, "New", srv.URL+"/new.xml")
+	oldPost := db.addPost(old, "Archived", "https://example.com/archived")
+	db.addWebSub(old, "https://hub.example/", "secret", "active", time.Time{})
+	ctx := context.Background()
+	err := db.SetFeedFetchFullContent(ctx, database.SetFeedFetchFullContentParams{ID: old.ID, FetchFullContent: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestState(db)
+	s.http = newTestHTTPClient(t, config.HTTPConfig{})
+
+	for i := int32(1); i < feedMoveThreshold; i++ {
+		scrapeStored(t, s, db, old)
+	}
+	scrapeStored(t, s, db, old)
+
+	if _, err := db.GetFeedByID(ctx, old.ID); err == nil {
+		t.Fatal("the moved feed still exists after merging")
+	}
+	if !db.follows[alice.ID][existing.ID] {
+		t.Error("the moved feed's follower doesn't follow the feed it was merged into")
+	}
+	if got := db.post(oldPost.ID); got.FeedID != existing.ID {
+		t.Errorf("the moved feed's post belongs to %s, want %s", got.FeedID, existing.ID)
+	}
+	if got, _ := db.GetFeedByID(ctx, existing.ID); !got.FetchFullContent {
+		t.Error("the full-content setting wasn't carried over")
+	}
+	if sub := db.webSub(existing.ID); sub.HubUrl != "https://hub.example/" || sub.State != "new" {
+		t.Errorf("WebSub subscription after the merge: %+v", sub)
+	}
+	// The new posts land on the merged feed, next to the archived one.
+	var posts int
+	for _, row := range db.rows(alice.ID) {
+		if row.FeedID != existing.ID {
+			t.Errorf("post %q belongs to %s", row.Title, row.FeedID)
+		}
+		posts++
+	}
+	if posts != 2 {
+		t.Errorf("alice sees %d posts, want 2", posts)
+	}
+}
+
+func TestFeedGone(t *testing.T) {
+	var moved atomic.Bool
+	srv := newMovingFeedServer(t, &moved)
+	db := newMemStore()
+	feed := db.addFeed(db.addUser("alice"), "Gone", srv.URL+"/gone.xml")
+	s := newTestState(db)
+	s.http = newTestHTTPClient(t, config.HTTPConfig{})
+
+	if got := scrapeStored(t, s, db, feed); !got.GoneAt.Valid {
+		t.Error("a 410 didn't mark the feed gone")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"sort"
+	"strings"
+)
+
+// shortIDLen is how many leading hex digits of a UUID are shown as its short
+// ID. Any unique prefix is accepted back, so collisions only cost typing.
+const shortIDLen = 8
+
+func shortID(id uuid.UUID) string {
+	return id.String()[:shortIDLen]
+}
+
+// resolveFeed picks the feed that ref refers to. In order of precedence ref
+// may be the exact URL, the case-insensitive name, a prefix of the name, or a
+// prefix of the feed's ID (at least 4 digits). The first rule that matches
+// anything decides; if it matches more than one feed the reference is
+// ambiguous.
+func resolveFeed(feeds []database.Feed, ref string) (database.Feed, error) {
+	lower := strings.ToLower(ref)
+	rules := []func(database.Feed) bool{
+		func(f database.Feed) bool { return f.Url == ref },
+		func(f database.Feed) bool { return strings.ToLower(f.Name) == lower },
+		func(f database.Feed) bool { return strings.HasPrefix(strings.ToLower(f.Name), lower) },
+		func(f database.Feed) bool { return len(ref) >= 4 && strings.HasPrefix(f.ID.String(), lower) },
+	}
+	for _, match := range rules {
+		var found []database.Feed
+		for _, feed := range feeds {
+			if match(feed) {
+				found = append(found, feed)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		}
+		sort.Slice(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+		var b strings.Builder
+		fmt.Fprintf(&b, "%q matches %d feeds - use a longer name, the ID or the URL:", ref, len(found))
+		for _, feed := range found {
+			fmt.Fprintf(&b, "\n  %s  %s  %s", shortID(feed.ID), feed.Name, feed.Url)
+		}
+		return database.Feed{}, usageError("%s", b.String())
+	}
+	return database.Feed{}, notFoundError("no feed matches %q", ref)
+}
+
+// resolvePost finds a post in the user's feeds by a prefix of its ID, as
+// printed by browse and posts.
+func resolvePost(ctx context.Context, s *state, user database.User, ref string) (database.GetPostsByIDPrefixRow, error) {
+	prefix := strings.ToLower(ref)
+	if len(prefix) < 4 || strings.Trim(prefix, "0123456789abcdef-") != "" {
+		return database.GetPostsByIDPrefixRow{}, usageError("invalid post ID %q (use at least 4 characters of the ID shown by browse)", ref)
+	}
+	posts, err := s.db.GetPostsByIDPrefix(ctx, database.GetPostsByIDPrefixParams{
+		UserID: user.ID,
+		Prefix: prefix,
+	})
+	if err != nil {
+		return database.GetPostsByIDPrefixRow{}, fmt.Errorf("failed to look up post: %w", err)
+	}
+	switch {
+	case len(posts) == 0:
+		return database.GetPostsByIDPrefixRow{}, notFoundError("no post %q in the feeds you follow", ref)
+	case len(posts) > 1:
+		var b strings.Builder
+		fmt.Fprintf(&b, "%q matches several posts - use a longer ID:", ref)
+		for _, post := range posts {
+			fmt.Fprintf(&b, "\n  %s  %s", shortID(post.ID), truncate(post.Title, 60))
+		}
+		return database.GetPostsByIDPrefixRow{}, usageError("%s", b.String())
+	}
+	return posts[0], nil
+}
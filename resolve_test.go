@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+)
+
+func TestResolveFeed(t *testing.T) {
+	db := newMemStore()
+	alice := db.addUser("alice")
+	goBlog := db.addFeed(alice, "Go Blog", "https://go.dev/blog/feed.atom")
+	goNews := db.addFeed(alice, "Go News", "https://golang.example/news.xml")
+	rust := db.addFeed(alice, "Rust Blog", "https://blog.rust-lang.org/feed.xml")
+	// "Blog" is also an ID prefix below, and a name prefix of this one.
+	blog := db.addFeed(alice, "Blogroll", "https://blogroll.example/rss")
+	db.setID(goBlog.ID, uuid.MustParse("b10c0000-0000-4000-8000-000000000001"))
+	db.setID(goNews.ID, uuid.MustParse("b10c0000-0000-4000-8000-000000000002"))
+	db.setID(rust.ID, uuid.MustParse("7e570000-0000-4000-8000-000000000003"))
+	db.setID(blog.ID, uuid.MustParse("abcd0000-0000-4000-8000-000000000004"))
+	feeds, err := db.GetFeedsForUser(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref      string
+		want     string // feed name
+		wantCode int
+		wantMsg  string
+	}{
+		{ref: "https://go.dev/blog/feed.atom", want: "Go Blog"},
+		{ref: "go blog", want: "Go Blog"},
+		{ref: "RUST", want: "Rust Blog"},
+		// An exact name beats a longer name it is a prefix of.
+		{ref: "Go News", want: "Go News"},
+		// A name prefix beats an ID prefix: "b10c" would match two IDs.
+		{ref: "blog", want: "Blogroll"},
+		{ref: "7e57", want: "Rust Blog"},
+		{ref: "7E570000-0000", want: "Rust Blog"},
+		{ref: "b10c0000-0000-4000-8000-000000000002", want: "Go News"},
+		{ref: "go", wantCode: exitUsage, wantMsg: `"go" matches 2 feeds`},
+		{ref: "b10c", wantCode: exitUsage, wantMsg: "b10c0000  Go Blog"},
+		// ID prefixes need 4 digits.
+		{ref: "7e5", wantCode: exitNotFound},
+		{ref: "https://go.dev/blog/", wantCode: exitNotFound},
+		{ref: "python", wantCode: exitNotFound, wantMsg: `no feed matches "python"`},
+	}
+	for _, tt := range tests {
+		got, err := resolveFeed(feeds, tt.ref)
+		if tt.wantCode != 0 {
+			if exitCode(err) != tt.wantCode || !strings.Contains(errString(err), tt.wantMsg) {
+				t.Errorf("resolveFeed(%q) = %q, %v; want exit code %d mentioning %q", tt.ref, got.Name, err, tt.wantCode, tt.wantMsg)
+			}
+			continue
+		}
+		if err != nil || got.Name != tt.want {
+			t.Errorf("resolveFeed(%q) = %q, %v; want %q", tt.ref, got.Name, err, tt.want)
+		}
+	}
+}
+
+func TestResolvePost(t *testing.T) {
+	db := newMemStore()
+	alice, bob := db.addUser("alice"), db.addUser("bob")
+	feed := db.addFeed(alice, "Go Blog", "https://go.dev/blog/feed.atom")
+	other := db.addFeed(bob, "Bob's feed", "https://bob.example/feed")
+	ids := map[string]database.Post{
+		"c0de0000-0000-4000-8000-000000000001": db.addPost(feed, "Go 1.22", "https://go.dev/blog/go1.22"),
+		"c0de0000-0000-4000-8000-000000000002": db.addPost(feed, "Range functions", "https://go.dev/blog/range"),
+		"f00d0000-0000-4000-8000-000000000003": db.addPost(feed, "Generics", "https://go.dev/blog/generics"),
+		"beef0000-0000-4000-8000-000000000004": db.addPost(other, "Bob only", "https://bob.example/1"),
+	}
+	for id, post := range ids {
+		db.setID(post.ID, uuid.MustParse(id))
+	}
+	s := newTestState(db)
+
+	tests := []struct {
+		ref      string
+		want     string // post title
+		wantCode int
+		wantMsg  string
+	}{
+		{ref: "f00d", want: "Generics"},
+		{ref: "F00D0000", want: "Generics"},
+		{ref: "c0de0000-0000-4000-8000-000000000002", want: "Range functions"},
+		{ref: "c0de", wantCode: exitUsage, wantMsg: "matches several posts"},
+		{ref: "c0d", wantCode: exitUsage, wantMsg: "at least 4 characters"},
+		{ref: "go-1.22", wantCode: exitUsage, wantMsg: "invalid post ID"},
+		// Posts of feeds the user doesn't follow don't exist for them.
+		{ref: "beef", wantCode: exitNotFound},
+		{ref: "dead", wantCode: exitNotFound},
+	}
+	for _, tt := range tests {
+		got, err := resolvePost(context.Background(), s, alice, tt.ref)
+		if tt.wantCode != 0 {
+			if exitCode(err) != tt.wantCode || !strings.Contains(errString(err), tt.wantMsg) {
+				t.Errorf("resolvePost(%q) = %q, %v; want exit code %d mentioning %q", tt.ref, got.Title, err, tt.wantCode, tt.wantMsg)
+			}
+			continue
+		}
+		if err != nil || got.Title != tt.want {
+			t.Errorf("resolvePost(%q) = %q, %v; want %q", tt.ref, got.Title, err, tt.want)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
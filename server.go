@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gator/internal/database"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+const defaultServeAddr = "localhost:8080"
+
+var (
+	errInvalidToken = errors.New("invalid token")
+	errTokenExpired = errors.New("token expired")
+)
+
+type authedHandler func(w http.ResponseWriter, r *http.Request, user database.User)
+
+// middlewareAuthenticated is the HTTP counterpart of middlewareLoggedIn: it
+// resolves the bearer token on the request to a user before calling handler.
+func middlewareAuthenticated(s *state, handler authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gator"`)
+			respondWithError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		user, err := authenticateToken(r.Context(), s, token, tokenKindAPI)
+		if errors.Is(err, errInvalidToken) || errors.Is(err, errTokenExpired) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gator", error="invalid_token"`)
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		} else if err != nil {
+			log.Printf("Failed to look up token: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "unable to authenticate")
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// authenticateToken resolves a raw token of the given kind to its user.
+func authenticateToken(ctx context.Context, s *state, token, kind string) (database.User, error) {
+	row, err := s.db.GetUserByAPIToken(ctx, hashAPIToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, errInvalidToken
+	} else if err != nil {
+		return database.User{}, err
+	}
+	return checkAPIToken(ctx, s, row, kind)
+}
+
+// checkAPIToken rejects tokens of another kind and expired tokens, and
+// records when a token was last used.
+func checkAPIToken(ctx context.Context, s *state, row database.GetUserByAPITokenRow, kind string) (database.User, error) {
+	if row.TokenKind != kind {
+		return database.User{}, errInvalidToken
+	}
+	if row.TokenExpiresAt.Valid && time.Now().UTC().After(row.TokenExpiresAt.Time) {
+		return database.User{}, errTokenExpired
+	}
+	err := s.db.TouchAPIToken(ctx, database.TouchAPITokenParams{
+		ID:         row.TokenID,
+		LastUsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("Failed to record token use: %+v", err)
+	}
+	return database.User{
+		ID:           row.ID,
+		CreatedAt:    row.CreatedAt,
+		UpdatedAt:    row.UpdatedAt,
+		Name:         row.Name,
+		PasswordHash: row.PasswordHash,
+	}, nil
+}
+
+// bearerToken extracts the API token from "Authorization: Bearer <token>", or
+// from "GoogleLogin auth=<token>" as sent by Google Reader clients.
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(auth, " ")
+	if !found {
+		return "", false
+	}
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+	case strings.EqualFold(scheme, "GoogleLogin"):
+		token, found = strings.CutPrefix(strings.TrimSpace(token), "auth=")
+		if !found {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to marshal JSON response: %+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	respondWithJSON(w, code, map[string]string{"error": msg})
+}
+
+func apiHandlerMe(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, struct {
+		ID        string    `json:"id"`
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+	}{user.ID.String(), user.Name, user.CreatedAt})
+}
+
+func newServeMux(s *state) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.Handle("GET /api/me", middlewareAuthenticated(s, apiHandlerMe))
+	mux.Handle("GET /users/{name}/feed.atom", middlewareAuthenticated(s, apiHandlerUserFeed(s, "atom")))
+	mux.Handle("GET /users/{name}/feed.rss", middlewareAuthenticated(s, apiHandlerUserFeed(s, "rss")))
+
+	mux.Handle("/fever/", apiHandlerFever(s))
+
+	mux.Handle("GET /websub/{feed}", apiHandlerWebSubVerify(s))
+	mux.Handle("POST /websub/{feed}", apiHandlerWebSubPush(s))
+
+	mux.Handle("/accounts/ClientLogin", apiHandlerReaderLogin(s))
+	reader := func(pattern string, handler authedHandler) {
+		mux.Handle(pattern, middlewareAuthenticated(s, handler))
+	}
+	reader("/reader/api/0/token", apiHandlerReaderToken)
+	reader("/reader/api/0/user-info", apiHandlerReaderUserInfo)
+	reader("/reader/api/0/tag/list", apiHandlerReaderTags)
+	reader("/reader/api/0/subscription/list", apiHandlerReaderSubscriptions(s))
+	reader("POST /reader/api/0/subscription/edit", apiHandlerReaderEditSubscription(s))
+	reader("POST /reader/api/0/subscription/quickadd", apiHandlerReaderQuickAdd(s))
+	reader("/reader/api/0/unread-count", apiHandlerReaderUnreadCount(s))
+	reader("/reader/api/0/stream/items/ids", apiHandlerReaderItemIDs(s))
+	reader("/reader/api/0/stream/items/contents", apiHandlerReaderItemContents(s))
+	reader("/reader/api/0/stream/contents", apiHandlerReaderStreamContents(s))
+	reader("/reader/api/0/stream/contents/{stream...}", apiHandlerReaderStreamContents(s))
+	reader("POST /reader/api/0/edit-tag", apiHandlerReaderEditTag(s))
+	reader("POST /reader/api/0/mark-all-as-read", apiHandlerReaderMarkAllRead(s))
+
+	registerWebRoutes(s, mux)
+	return mux
+}
+
+func serveFlags(fs *flag.FlagSet) {
+	fs.String("public-url", "", "URL this server is reachable at from the internet; enables WebSub push subscriptions")
+}
+
+func handlerServe(s *state, cmd command) error {
+	addr := defaultServeAddr
+	if len(cmd.args) > 0 {
+		addr = cmd.args[0]
+	}
+	publicURL := cmd.stringFlag("public-url")
+	if publicURL != "" {
+		if u, err := url.Parse(publicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return usageError("invalid --public-url %q (use an absolute http or https URL)", publicURL)
+		}
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newServeMux(s),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	fmt.Printf("Serving on http://%s\n", addr)
+
+	websubCtx, stopWebSub := context.WithCancel(context.Background())
+	defer stopWebSub()
+	if publicURL != "" {
+		go runWebSub(websubCtx, s, publicURL)
+		fmt.Printf("Subscribing to WebSub hubs with callbacks at %s/websub/\n", strings.TrimRight(publicURL, "/"))
+	}
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("server stopped: %w", err)
+	case <-stop:
+		fmt.Println("stopping server")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"gator/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const (
+	testToken   = "gator_test"
+	testSession = "gator_session"
+)
+
+// replayStep is one request of a recorded client session and what the
+// response must (and must not) contain.
+type replayStep struct {
+	name       string
+	method     string
+	path       string
+	form       url.Values
+	auth       bool // send testToken as "GoogleLogin auth=..."
+	wantStatus int
+	want       []string
+	notWant    []string
+	check      func(t *testing.T, body []byte)
+}
+
+func newTestState(db *memStore) *state {
+	return &state{db: db, output: outputText, Config: &config.Config{}}
+}
+
+// replay sends steps to handler in order; later steps see the state
+// earlier ones left behind.
+func replay(t *testing.T, handler http.Handler, steps []replayStep) {
+	t.Helper()
+	for _, step := range steps {
+		var body *strings.Reader
+		if step.form != nil {
+			body = strings.NewReader(step.form.Encode())
+		} else {
+			body = strings.NewReader("")
+		}
+		req := httptest.NewRequest(step.method, step.path, body)
+		if step.form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if step.auth {
+			req.Header.Set("Authorization", "GoogleLogin auth="+testToken)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		got := rec.Body.String()
+		wantStatus := step.wantStatus
+		if wantStatus == 0 {
+			wantStatus = http.StatusOK
+		}
+		if rec.Code != wantStatus {
+			t.Fatalf("%s: status %d, want %d (body %q)", step.name, rec.Code, wantStatus, got)
+		}
+		for _, want := range step.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: body lacks %q:\n%s", step.name, want, got)
+			}
+		}
+		for _, notWant := range step.notWant {
+			if strings.Contains(got, notWant) {
+				t.Errorf("%s: body unexpectedly contains %q:\n%s", step.name, notWant, got)
+			}
+		}
+		if step.check != nil {
+			step.check(t, rec.Body.Bytes())
+		}
+	}
+}
+
+// newSyncFixture is alice following two feeds with three posts between
+// them, and bob following a third feed alice must never see.
+func newSyncFixture() *memStore {
+	db := newMemStore()
+	alice := db.addUser("alice")
+	db.addToken(alice, testToken, tokenKindAPI)
+	db.addToken(alice, testSession, tokenKindSession)
+	golang := db.addFeed(alice, "Go Blog", "https://go.dev/blog/feed.atom")
+	rust := db.addFeed(alice, "Rust Blog", "https://blog.rust-lang.org/feed.xml")
+	db.addPost(golang, "Go 1.22", "https://go.dev/blog/go1.22")
+	db.addPost(golang, "Range functions", "https://go.dev/blog/range-functions")
+	db.addPost(rust, "Rust 1.80", "https://blog.rust-lang.org/1.80")
+
+	bob := db.addUser("bob")
+	db.addToken(bob, "gator_bob", tokenKindAPI)
+	other := db.addFeed(bob, "Bob's feed", "https://bob.example/feed")
+	db.addPost(other, "Bob only", "https://bob.example/1")
+	return db
+}
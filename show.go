@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/term"
+	"os"
+	"strings"
+	"time"
+)
+
+func showFlags(fs *flag.FlagSet) {
+	fs.Int("width", 0, "wrap text at this many columns (0 = terminal width, at most 100)")
+}
+
+func handlerShow(s *state, cmd command, user database.User) error {
+	width := cmd.intFlag("width")
+	if width <= 0 {
+		width = 80
+		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+			width = min(w, 100)
+		}
+	}
+
+	ctx := context.Background()
+	post, err := resolvePost(ctx, s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.Join(wrapText(post.Title, width), "\n"))
+	fmt.Println(strings.Repeat("=", min(len([]rune(post.Title)), width)))
+	fmt.Printf("Feed:      %s\n", post.FeedName)
+	if post.Author.Valid {
+		fmt.Printf("Author:    %s\n", post.Author.String)
+	}
+	published := "unknown"
+	if post.PublishedAt.Valid {
+		published = post.PublishedAt.Time.Format(time.RFC1123)
+	}
+	fmt.Printf("Published: %s\n", published)
+	fmt.Printf("Fetched:   %s\n", post.CreatedAt.Format(time.RFC1123))
+	fmt.Printf("Link:      %s\n", postLink(post.Url, post.OriginalUrl))
+	fmt.Printf("ID:        %s\n", post.ID)
+	categories, err := s.db.GetCategoriesForPosts(ctx, []uuid.UUID{post.ID})
+	if err != nil {
+		return fmt.Errorf("failed to get categories: %w", err)
+	}
+	if len(categories) > 0 {
+		names := make([]string, len(categories))
+		for i, category := range categories {
+			names[i] = category.Name
+		}
+		fmt.Printf("Tags:      %s\n", strings.Join(names, ", "))
+	}
+	enclosures, err := s.db.GetEnclosuresForPost(ctx, post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get enclosures: %w", err)
+	}
+	for _, enclosure := range enclosures {
+		fmt.Printf("Enclosure: %s (%s)\n", enclosure.Url, describeEnclosure(enclosure))
+	}
+
+	body := postHTML(post.Description, post.Content)
+	if strings.TrimSpace(body) == "" {
+		fmt.Println("\n(no description)")
+		return nil
+	}
+	text, links := htmlToText(body, width)
+	fmt.Printf("\n%s\n", text)
+	if len(links) > 0 {
+		fmt.Println("\nLinks:")
+		for i, link := range links {
+			fmt.Printf("[%d] %s\n", i+1, link)
+		}
+	}
+	return nil
+}
+
+func describeEnclosure(e database.PostEnclosure) string {
+	parts := []string{}
+	if e.MimeType != "" {
+		parts = append(parts, e.MimeType)
+	}
+	if e.Length > 0 {
+		parts = append(parts, formatSize(e.Length))
+	}
+	if len(parts) == 0 {
+		return "unknown type"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
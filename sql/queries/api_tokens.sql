@@ -0,0 +1,44 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, created_at, updated_at, user_id, name, token_hash, expires_at, fever_key, kind)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetAPITokensForUser :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+AND kind = 'api'
+ORDER BY created_at;
+
+-- name: RevokeAPIToken :execrows
+DELETE FROM api_tokens
+WHERE id = $1
+AND user_id = $2;
+
+-- name: GetUserByAPIToken :one
+SELECT users.*,
+       api_tokens.id AS token_id,
+       api_tokens.expires_at AS token_expires_at,
+       api_tokens.kind AS token_kind
+FROM api_tokens
+JOIN users ON users.id = api_tokens.user_id
+WHERE api_tokens.token_hash = $1;
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM api_tokens
+WHERE user_id = $1
+AND kind = 'session'
+AND expires_at < sqlc.arg(now)::timestamp;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens
+SET last_used_at = $2
+WHERE id = $1;
+
+-- name: GetUserByFeverKey :one
+SELECT users.*,
+       api_tokens.id AS token_id,
+       api_tokens.expires_at AS token_expires_at,
+       api_tokens.kind AS token_kind
+FROM api_tokens
+JOIN users ON users.id = api_tokens.user_id
+WHERE api_tokens.fever_key = $1;
@@ -0,0 +1,24 @@
+-- name: SetFeedCredentials :exec
+INSERT INTO feed_credentials (feed_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (feed_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+    updated_at = NOW();
+
+-- name: GetFeedCredentials :one
+SELECT * FROM feed_credentials
+WHERE feed_id = $1;
+
+-- name: CountFeedCredentials :one
+SELECT COUNT(*) FROM feed_credentials;
+
+-- name: DeleteFeedCredentials :exec
+DELETE FROM feed_credentials
+WHERE feed_id = $1;
+
+-- name: MoveFeedCredentials :exec
+UPDATE feed_credentials
+SET feed_id = sqlc.arg(into_id),
+    updated_at = NOW()
+WHERE feed_id = sqlc.arg(from_id)
+AND NOT EXISTS (SELECT 1 FROM feed_credentials WHERE feed_id = sqlc.arg(into_id));
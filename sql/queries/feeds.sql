@@ -1,12 +1,14 @@
 -- name: AddFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, link, description)
 VALUES (
         $1,
         $2,
         $3,
         $4,
         $5,
-        $6
+        $6,
+        $7,
+        $8
        )
 RETURNING *;
 
@@ -17,7 +19,7 @@ DELETE FROM feeds;
 DELETE FROM feed_follows;
 
 -- name: GetFeeds :many
-SELECT name, url, user_id FROM feeds;
+SELECT * FROM feeds;
 
 -- name: CreateFeedFollow :one
 WITH inserted_follow AS (
@@ -36,7 +38,8 @@ JOIN feeds ON feeds.id = inserted_follow.feed_id;
 -- name: GetFeedFollowsForUser :many
 SELECT feed_follows.*,
        users.name as user_name,
-       feeds.name as feed_name
+       feeds.name as feed_name,
+       feeds.url as feed_url
 FROM feed_follows
 JOIN users ON users.id = feed_follows.user_id
 JOIN feeds ON feeds.id = feed_follows.feed_id
@@ -67,8 +70,95 @@ SET updated_at = Now(),
     last_fetched_at = Now()
 WHERE feeds.id = $1;
 
--- name: GetNextFeedToFetch :one
+-- name: GetNextFeedsToFetch :many
 SELECT *
 FROM feeds
+WHERE gone_at IS NULL
+-- Feeds pushed by a WebSub hub are only polled daily, as a safety net.
+AND NOT EXISTS (
+    SELECT 1 FROM websub_subscriptions AS ws
+    WHERE ws.feed_id = feeds.id
+    AND ws.state = 'active'
+    AND ws.lease_expires_at > NOW()
+    AND feeds.last_fetched_at > NOW() - INTERVAL '1 day'
+)
 ORDER BY last_fetched_at NULLS FIRST,
-         updated_at LIMIT 1;
+         updated_at LIMIT $1;
+
+-- name: GetFeedsForUser :many
+SELECT feeds.*
+FROM feeds
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.name;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+
+-- name: GetFeedBySeq :one
+SELECT * FROM feeds
+WHERE seq = $1;
+
+-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET fetch_full_content = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET link = $2,
+    description = $3,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET moved_count = CASE WHEN moved_to = $2 THEN moved_count + 1 ELSE 1 END,
+    moved_to = $2
+WHERE id = $1
+RETURNING moved_count;
+
+-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET moved_to = NULL,
+    moved_count = 0
+WHERE id = $1
+  AND moved_to IS NOT NULL;
+
+-- name: SetFeedURL :exec
+UPDATE feeds
+SET url = $2,
+    moved_to = NULL,
+    moved_count = 0,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MergeFeed :exec
+WITH moved_follows AS (
+    INSERT INTO feed_follows (user_id, feed_id)
+    SELECT user_id, sqlc.arg(into_id)::uuid
+    FROM feed_follows
+    WHERE feed_id = sqlc.arg(from_id)
+    ON CONFLICT (user_id, feed_id) DO NOTHING
+), full_content AS (
+    UPDATE feeds
+    SET fetch_full_content = true,
+        updated_at = NOW()
+    WHERE id = sqlc.arg(into_id)
+    AND (SELECT fetch_full_content FROM feeds WHERE id = sqlc.arg(from_id))
+)
+UPDATE posts
+SET feed_id = sqlc.arg(into_id)
+WHERE feed_id = sqlc.arg(from_id);
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: MarkFeedGone :exec
+UPDATE feeds
+SET gone_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
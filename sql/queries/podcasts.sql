@@ -0,0 +1,41 @@
+-- name: GetEpisodesForUser :many
+SELECT p.id AS post_id,
+       p.title,
+       p.published_at,
+       p.created_at,
+       f.name AS feed_name,
+       f.id AS feed_id,
+       f.url AS feed_url,
+       e.url,
+       e.mime_type,
+       e.length,
+       e.duration,
+       e.episode,
+       es.downloaded_at,
+       es.file_path,
+       es.played_at
+FROM posts AS p
+JOIN feeds AS f ON f.id = p.feed_id
+JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
+JOIN post_enclosures AS e ON e.post_id = p.id
+LEFT JOIN episode_states AS es ON es.post_id = p.id AND es.user_id = ff.user_id
+WHERE ff.user_id = sqlc.arg(user_id)
+AND (sqlc.arg(feed_seq)::bigint = 0 OR f.seq = sqlc.arg(feed_seq)::bigint)
+AND (e.mime_type LIKE 'audio/%' OR e.mime_type LIKE 'video/%' OR e.mime_type = '')
+ORDER BY f.name,
+         f.id,
+         p.published_at DESC NULLS LAST,
+         p.created_at DESC,
+         e.url;
+
+-- name: MarkEpisodeDownloaded :exec
+INSERT INTO episode_states (user_id, post_id, downloaded_at, file_path)
+VALUES ($1, $2, NOW(), $3)
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET downloaded_at = NOW(), file_path = EXCLUDED.file_path;
+
+-- name: MarkEpisodePlayed :exec
+INSERT INTO episode_states (user_id, post_id, played_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET played_at = NOW();
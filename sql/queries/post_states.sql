@@ -0,0 +1,95 @@
+-- name: GetPostsWithStateForUser :many
+SELECT p.*,
+       feeds.seq AS feed_seq,
+       feeds.name AS feed_name,
+       feeds.url AS feed_url,
+       (ps.read_at IS NOT NULL)::boolean AS is_read,
+       (ps.starred_at IS NOT NULL)::boolean AS is_starred
+FROM posts AS p
+JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
+JOIN feeds ON feeds.id = p.feed_id
+LEFT JOIN post_states AS ps ON ps.post_id = p.id AND ps.user_id = ff.user_id
+WHERE ff.user_id = sqlc.arg(user_id)
+AND p.seq > sqlc.arg(since_seq)::bigint
+AND (sqlc.arg(max_seq)::bigint = 0 OR p.seq < sqlc.arg(max_seq)::bigint)
+AND (sqlc.arg(feed_seq)::bigint = 0 OR feeds.seq = sqlc.arg(feed_seq)::bigint)
+AND (NOT sqlc.arg(unread_only)::boolean OR ps.read_at IS NULL)
+AND (NOT sqlc.arg(starred_only)::boolean OR ps.starred_at IS NOT NULL)
+AND (sqlc.narg(newer_than)::timestamp IS NULL OR p.created_at > sqlc.narg(newer_than)::timestamp)
+ORDER BY
+    CASE WHEN sqlc.arg(oldest_first)::boolean THEN p.seq END ASC,
+    p.seq DESC
+LIMIT sqlc.arg(max_items);
+
+-- name: GetPostsWithStateBySeq :many
+SELECT p.*,
+       feeds.seq AS feed_seq,
+       feeds.name AS feed_name,
+       feeds.url AS feed_url,
+       (ps.read_at IS NOT NULL)::boolean AS is_read,
+       (ps.starred_at IS NOT NULL)::boolean AS is_starred
+FROM posts AS p
+JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
+JOIN feeds ON feeds.id = p.feed_id
+LEFT JOIN post_states AS ps ON ps.post_id = p.id AND ps.user_id = ff.user_id
+WHERE ff.user_id = $1
+AND p.seq = ANY(sqlc.arg(seqs)::bigint[])
+ORDER BY p.seq DESC;
+
+-- name: GetPostSeqsForUser :many
+SELECT p.seq
+FROM posts AS p
+JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
+LEFT JOIN post_states AS ps ON ps.post_id = p.id AND ps.user_id = ff.user_id
+WHERE ff.user_id = $1
+AND (NOT sqlc.arg(unread_only)::boolean OR ps.read_at IS NULL)
+AND (NOT sqlc.arg(starred_only)::boolean OR ps.starred_at IS NOT NULL)
+ORDER BY p.seq DESC;
+
+-- name: GetUnreadCountsForUser :many
+SELECT feeds.seq AS feed_seq,
+       COUNT(p.id) AS unread,
+       MAX(p.created_at)::timestamp AS newest
+FROM feed_follows AS ff
+JOIN feeds ON feeds.id = ff.feed_id
+JOIN posts AS p ON p.feed_id = ff.feed_id
+LEFT JOIN post_states AS ps ON ps.post_id = p.id AND ps.user_id = ff.user_id
+WHERE ff.user_id = $1
+AND ps.read_at IS NULL
+GROUP BY feeds.seq;
+
+-- name: MarkPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET read_at = COALESCE(post_states.read_at, NOW());
+
+-- name: MarkPostUnread :exec
+UPDATE post_states
+SET read_at = NULL
+WHERE user_id = $1
+AND post_id = $2;
+
+-- name: StarPost :exec
+INSERT INTO post_states (user_id, post_id, starred_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET starred_at = COALESCE(post_states.starred_at, NOW());
+
+-- name: UnstarPost :exec
+UPDATE post_states
+SET starred_at = NULL
+WHERE user_id = $1
+AND post_id = $2;
+
+-- name: MarkPostsReadBefore :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT ff.user_id, p.id, NOW()
+FROM posts AS p
+JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
+JOIN feeds ON feeds.id = p.feed_id
+WHERE ff.user_id = sqlc.arg(user_id)
+AND p.created_at <= sqlc.arg(before)
+AND (sqlc.arg(feed_seq)::bigint = 0 OR feeds.seq = sqlc.arg(feed_seq)::bigint)
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET read_at = COALESCE(post_states.read_at, NOW());
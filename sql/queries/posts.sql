@@ -1,6 +1,6 @@
 -- name: CreatePost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, original_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
 
 -- name: GetPostsForUser :many
 SELECT p.*
@@ -23,3 +23,56 @@ ORDER BY
     p.published_at DESC
 LIMIT $2
 OFFSET $4;
+
+-- name: GetPostsForUserFiltered :many
+SELECT p.*
+FROM posts AS p
+JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
+WHERE ff.user_id = sqlc.arg(user_id)
+AND (sqlc.arg(author)::text = '' OR p.author ILIKE '%' || sqlc.arg(author)::text || '%')
+AND (sqlc.arg(category)::text = '' OR EXISTS (
+    SELECT 1 FROM post_categories AS pc
+    WHERE pc.post_id = p.id
+    AND lower(pc.name) = lower(sqlc.arg(category)::text)
+))
+ORDER BY
+    p.published_at DESC NULLS LAST,
+    p.created_at DESC
+LIMIT sqlc.arg(max_posts);
+
+-- name: GetPostsByIDPrefix :many
+SELECT p.*, f.name AS feed_name, f.url AS feed_url
+FROM posts AS p
+JOIN feeds AS f ON f.id = p.feed_id
+JOIN feed_follows AS ff ON ff.feed_id = p.feed_id
+WHERE ff.user_id = sqlc.arg(user_id)
+AND p.id::text LIKE sqlc.arg(prefix)::text || '%'
+ORDER BY p.published_at DESC NULLS LAST
+LIMIT 10;
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: CreatePostEnclosure :exec
+INSERT INTO post_enclosures (post_id, url, mime_type, length, duration, episode)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT DO NOTHING;
+
+-- name: GetCategoriesForPosts :many
+SELECT post_id, name
+FROM post_categories
+WHERE post_id = ANY(sqlc.arg(post_ids)::uuid[])
+ORDER BY post_id, name;
+
+-- name: GetEnclosuresForPost :many
+SELECT * FROM post_enclosures
+WHERE post_id = $1
+ORDER BY url;
@@ -1,10 +1,11 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash)
 VALUES (
         $1,
         $2,
         $3,
-        $4
+        $4,
+        $5
        )
 RETURNING *;
 
@@ -21,3 +22,9 @@ SELECT * FROM users;
 -- name: GetUserName :one
 SELECT name FROM users
 WHERE id = $1;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET updated_at = NOW(),
+    password_hash = $2
+WHERE id = $1;
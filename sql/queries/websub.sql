@@ -0,0 +1,55 @@
+-- name: UpsertWebSubHub :exec
+INSERT INTO websub_subscriptions (feed_id, hub_url, topic_url, secret)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    state = 'new',
+    requested_at = NULL,
+    updated_at = NOW()
+WHERE websub_subscriptions.hub_url <> EXCLUDED.hub_url
+   OR websub_subscriptions.topic_url <> EXCLUDED.topic_url;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions
+WHERE feed_id = $1;
+
+-- name: GetWebSubSubscriptionsDue :many
+SELECT * FROM websub_subscriptions
+WHERE (requested_at IS NULL OR requested_at < NOW() - INTERVAL '1 hour')
+AND (state IN ('new', 'pending')
+     OR (state = 'active' AND lease_expires_at < NOW() + INTERVAL '1 day' AND updated_at < NOW() - INTERVAL '1 hour')
+     OR (state = 'denied' AND updated_at < NOW() - INTERVAL '1 day'));
+
+-- name: MarkWebSubRequested :exec
+UPDATE websub_subscriptions
+SET state = CASE WHEN state = 'active' THEN state ELSE 'pending' END,
+    requested_at = NOW(),
+    updated_at = NOW()
+WHERE feed_id = $1;
+
+-- name: ActivateWebSub :execrows
+UPDATE websub_subscriptions
+SET state = 'active',
+    lease_expires_at = $2,
+    requested_at = NULL,
+    updated_at = NOW()
+WHERE feed_id = $1
+AND state IN ('pending', 'active')
+AND requested_at > NOW() - INTERVAL '1 hour';
+
+-- name: DenyWebSub :exec
+UPDATE websub_subscriptions
+SET state = 'denied',
+    updated_at = NOW()
+WHERE feed_id = $1;
+
+-- name: MoveWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET feed_id = sqlc.arg(into_id),
+    state = 'new',
+    requested_at = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE feed_id = sqlc.arg(from_id)
+AND NOT EXISTS (SELECT 1 FROM websub_subscriptions WHERE feed_id = sqlc.arg(into_id));
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- Sync APIs (Fever, Google Reader) identify feeds and items by integer.
+ALTER TABLE feeds
+    ADD COLUMN seq BIGSERIAL NOT NULL UNIQUE;
+ALTER TABLE posts
+    ADD COLUMN seq BIGSERIAL NOT NULL UNIQUE;
+
+CREATE TABLE post_states (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    read_at TIMESTAMP,
+    starred_at TIMESTAMP,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- Fever clients authenticate with md5("<user name>:<api token>"); like the
+-- token itself, that key is stored as its SHA-256.
+ALTER TABLE api_tokens
+    ADD COLUMN fever_key TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE api_tokens
+    DROP COLUMN fever_key;
+DROP TABLE post_states;
+ALTER TABLE posts
+    DROP COLUMN seq;
+ALTER TABLE feeds
+    DROP COLUMN seq;
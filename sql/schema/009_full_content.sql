@@ -0,0 +1,13 @@
+-- +goose Up
+-- Feeds that only carry teasers can opt in to having each new post's linked
+-- article downloaded and its main content extracted into posts.content.
+ALTER TABLE feeds
+    ADD COLUMN fetch_full_content BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE posts
+    ADD COLUMN content TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+    DROP COLUMN content;
+ALTER TABLE feeds
+    DROP COLUMN fetch_full_content;
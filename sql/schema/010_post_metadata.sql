@@ -0,0 +1,25 @@
+-- +goose Up
+-- Item metadata beyond title/link/description: dc:creator or <author>,
+-- <category> and <enclosure> (podcast audio, attachments).
+ALTER TABLE posts
+    ADD COLUMN author TEXT;
+
+CREATE TABLE post_categories (
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    PRIMARY KEY (post_id, name)
+);
+
+CREATE TABLE post_enclosures (
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    mime_type TEXT NOT NULL DEFAULT '',
+    length BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE post_enclosures;
+DROP TABLE post_categories;
+ALTER TABLE posts
+    DROP COLUMN author;
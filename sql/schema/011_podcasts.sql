@@ -0,0 +1,20 @@
+-- +goose Up
+-- itunes: episode metadata, kept with the enclosure it describes.
+ALTER TABLE post_enclosures
+    ADD COLUMN duration INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN episode INTEGER;
+
+CREATE TABLE episode_states (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    downloaded_at TIMESTAMP,
+    file_path TEXT,
+    played_at TIMESTAMP,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE episode_states;
+ALTER TABLE post_enclosures
+    DROP COLUMN episode,
+    DROP COLUMN duration;
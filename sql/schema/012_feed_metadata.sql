@@ -0,0 +1,11 @@
+-- +goose Up
+-- The channel's own <link> (usually the website) and <description>,
+-- refreshed on every successful scrape.
+ALTER TABLE feeds
+    ADD COLUMN link TEXT,
+    ADD COLUMN description TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN description,
+    DROP COLUMN link;
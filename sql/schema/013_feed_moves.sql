@@ -0,0 +1,14 @@
+-- +goose Up
+-- A feed that keeps permanently redirecting to the same URL is moved there
+-- once moved_count reaches a threshold; a feed answering 410 Gone is no
+-- longer fetched.
+ALTER TABLE feeds
+    ADD COLUMN moved_to TEXT,
+    ADD COLUMN moved_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN gone_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN gone_at,
+    DROP COLUMN moved_count,
+    DROP COLUMN moved_to;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Basic auth, custom headers and cookies for feeds that need them, as one
+-- AES-GCM sealed JSON document per feed. The key lives in the config file,
+-- never in the database.
+CREATE TABLE feed_credentials (
+    feed_id UUID PRIMARY KEY REFERENCES feeds (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    secret BYTEA NOT NULL
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
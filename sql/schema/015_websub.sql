@@ -0,0 +1,18 @@
+-- +goose Up
+-- WebSub subscriptions for feeds that advertise a hub. state is one of
+-- new (hub discovered), pending (subscription requested), active (verified
+-- by the hub until lease_expires_at) or denied.
+CREATE TABLE websub_subscriptions (
+    feed_id UUID PRIMARY KEY REFERENCES feeds (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    state TEXT NOT NULL DEFAULT 'new',
+    requested_at TIMESTAMP,
+    lease_expires_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
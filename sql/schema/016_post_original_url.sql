@@ -0,0 +1,10 @@
+-- +goose Up
+-- posts.url is the item's link resolved against the feed and canonicalized,
+-- so the same article is stored once; original_url keeps the link as the
+-- feed gave it, when that differs, for display.
+ALTER TABLE posts
+    ADD COLUMN original_url TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+    DROP COLUMN original_url;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Web UI sessions are tokens of their own kind: they aren't listed by
+-- "gator token list", can't be used with the APIs, and are deleted once
+-- expired.
+ALTER TABLE api_tokens
+    ADD COLUMN kind TEXT NOT NULL DEFAULT 'api';
+-- Sessions from before kinds existed can't be told apart from API tokens
+-- for certain, so they stay API tokens but are ended now: web users log in
+-- again, and an API token that merely shares the name shows up as expired
+-- in "gator token list" rather than silently becoming a session.
+UPDATE api_tokens
+SET expires_at = NOW() AT TIME ZONE 'UTC',
+    updated_at = NOW() AT TIME ZONE 'UTC'
+WHERE name = 'web session'
+AND fever_key IS NULL
+AND expires_at > NOW() AT TIME ZONE 'UTC';
+
+-- +goose Down
+DELETE FROM api_tokens
+WHERE kind = 'session';
+ALTER TABLE api_tokens
+    DROP COLUMN kind;
@@ -0,0 +1,87 @@
+-- +goose Up
+-- Posts stored before 016 kept their links as the feed gave them, so
+-- fetching the same articles again stored them a second time under their
+-- canonical URL. Canonicalize the old rows as canonicalURL does (relative
+-- links can't be resolved here and are left alone) and, where that makes
+-- two posts one, keep the older with the read, starred and episode state
+-- of both.
+-- +goose StatementBegin
+CREATE FUNCTION canonical_post_url(link TEXT) RETURNS TEXT AS $$
+DECLARE
+    parts TEXT[];
+    host TEXT;
+    query TEXT;
+BEGIN
+    -- scheme, userinfo, host[:port], path, ?query; the fragment is dropped.
+    parts := regexp_match(link, '^([A-Za-z][A-Za-z0-9+.-]*)://([^/?#@]*@)?([^/?#]*)([^?#]*)(\?[^#]*)?');
+    IF parts IS NULL THEN
+        RETURN link;
+    END IF;
+    host := lower(parts[3]);
+    IF (lower(parts[1]) = 'http' AND host LIKE '%:80') OR (lower(parts[1]) = 'https' AND host LIKE '%:443') THEN
+        host := regexp_replace(host, ':[0-9]+$', '');
+    END IF;
+    query := array_to_string(ARRAY(
+        SELECT param
+        FROM unnest(string_to_array(substr(parts[5], 2), '&')) WITH ORDINALITY AS p (param, n)
+        WHERE param <> ''
+        AND lower(split_part(param, '=', 1)) NOT LIKE 'utm\_%'
+        AND lower(split_part(param, '=', 1)) NOT IN
+            ('fbclid', 'gclid', 'dclid', 'msclkid', 'yclid', 'igshid', 'mc_cid', 'mc_eid', '_hsenc', '_hsmi')
+        ORDER BY n
+    ), '&');
+    RETURN lower(parts[1]) || '://' || COALESCE(parts[2], '') || host
+        || CASE WHEN parts[4] = '' THEN '/' ELSE parts[4] END
+        || CASE WHEN query = '' THEN '' ELSE '?' || query END;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+CREATE TEMPORARY TABLE canonical_posts ON COMMIT DROP AS
+SELECT id, url, canonical,
+       row_number() OVER (PARTITION BY canonical ORDER BY created_at, seq) AS rank
+FROM (SELECT id, url, created_at, seq, canonical_post_url(url) AS canonical FROM posts) AS p;
+
+CREATE TEMPORARY TABLE post_merges ON COMMIT DROP AS
+SELECT dup.id AS from_id, keep.id AS into_id
+FROM canonical_posts dup
+JOIN canonical_posts keep ON keep.canonical = dup.canonical AND keep.rank = 1
+WHERE dup.rank > 1;
+
+INSERT INTO post_states (user_id, post_id, read_at, starred_at)
+SELECT s.user_id, m.into_id, MIN(s.read_at), MIN(s.starred_at)
+FROM post_states s
+JOIN post_merges m ON m.from_id = s.post_id
+GROUP BY s.user_id, m.into_id
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at),
+    starred_at = COALESCE(post_states.starred_at, EXCLUDED.starred_at);
+
+INSERT INTO episode_states (user_id, post_id, downloaded_at, file_path, played_at)
+SELECT s.user_id, m.into_id, MIN(s.downloaded_at), MIN(s.file_path), MIN(s.played_at)
+FROM episode_states s
+JOIN post_merges m ON m.from_id = s.post_id
+GROUP BY s.user_id, m.into_id
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET downloaded_at = COALESCE(episode_states.downloaded_at, EXCLUDED.downloaded_at),
+    file_path = COALESCE(episode_states.file_path, EXCLUDED.file_path),
+    played_at = COALESCE(episode_states.played_at, EXCLUDED.played_at);
+
+DELETE FROM posts
+WHERE id IN (SELECT from_id FROM post_merges);
+
+UPDATE posts
+SET url = c.canonical,
+    original_url = COALESCE(posts.original_url, posts.url),
+    updated_at = NOW()
+FROM canonical_posts c
+WHERE c.id = posts.id
+AND c.rank = 1
+AND c.canonical <> posts.url;
+
+DROP FUNCTION canonical_post_url(TEXT);
+
+-- +goose Down
+-- The canonical URLs work as well as the originals, which are kept in
+-- original_url; merged posts can't be split again, so there is nothing to
+-- undo.
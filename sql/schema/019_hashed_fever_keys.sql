@@ -0,0 +1,12 @@
+-- +goose Up
+-- Fever keys were stored as clients send them, which made the table as good
+-- as a list of credentials. Keep only their SHA-256, as for tokens.
+UPDATE api_tokens
+SET fever_key = encode(sha256(convert_to(fever_key, 'UTF8')), 'hex')
+WHERE fever_key IS NOT NULL;
+
+-- +goose Down
+-- The keys can't be recovered from their hashes; tokens created before
+-- this migration need recreating for Fever clients after going back.
+UPDATE api_tokens
+SET fever_key = NULL;
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+const apiTokenPrefix = "gator_"
+
+// Token kinds: API tokens are created with "gator token create"; session
+// tokens back web UI logins and only authenticate the web UI.
+const (
+	tokenKindAPI     = "api"
+	tokenKindSession = "session"
+)
+
+// newAPIToken returns a fresh random token. Only its hash is ever stored.
+func newAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// hashAPIToken is what gets stored in api_tokens.token_hash. Tokens carry
+// 256 bits of randomness, so a plain SHA-256 is enough (no salt/stretching).
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// feverKey is the api_key a Fever client sends when configured with the
+// user's name and an API token as its password.
+func feverKey(userName, token string) string {
+	sum := md5.Sum([]byte(userName + ":" + token))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFeverKey is what gets stored in api_tokens.fever_key. The key works
+// as a credential on its own, so like the token it is only kept hashed.
+func hashFeverKey(key string) string {
+	return hashAPIToken(strings.ToLower(key))
+}
+
+func tokenCreateFlags(fs *flag.FlagSet) {
+	fs.String("name", "default", "label to recognise the token by")
+	fs.Duration("expires", 0, "lifetime of the token, e.g. 720h (0 = never expires)")
+}
+
+func handlerTokenCreate(s *state, cmd command, user database.User) error {
+	name, expires := cmd.stringFlag("name"), cmd.durationFlag("expires")
+	if expires < 0 {
+		return usageError("invalid --expires value: %s", expires)
+	}
+
+	token, err := newAPIToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+	now := time.Now().UTC()
+	var expiresAt sql.NullTime
+	if expires > 0 {
+		expiresAt = sql.NullTime{Time: now.Add(expires), Valid: true}
+	}
+
+	ctx := context.Background()
+	created, err := s.db.CreateAPIToken(ctx, database.CreateAPITokenParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		Name:      name,
+		TokenHash: hashAPIToken(token),
+		ExpiresAt: expiresAt,
+		FeverKey:  sql.NullString{String: hashFeverKey(feverKey(user.Name, token)), Valid: true},
+		Kind:      tokenKindAPI,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	fmt.Printf("Token %s (%s) created for %s\n", created.ID, created.Name, user.Name)
+	fmt.Println("Store it now - it will not be shown again:")
+	fmt.Println(token)
+	return nil
+}
+
+func handlerTokenList(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	tokens, err := s.db.GetAPITokensForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get tokens: %w", err)
+	}
+	type tokenRecord struct {
+		ID         uuid.UUID  `json:"id"`
+		Name       string     `json:"name"`
+		CreatedAt  time.Time  `json:"created_at"`
+		ExpiresAt  *time.Time `json:"expires_at"`
+		LastUsedAt *time.Time `json:"last_used_at"`
+	}
+	out := listing{Columns: []string{"id", "name", "created_at", "expires_at", "last_used_at"}}
+	records := make([]tokenRecord, 0, len(tokens))
+	for _, t := range tokens {
+		record := tokenRecord{ID: t.ID, Name: t.Name, CreatedAt: t.CreatedAt.UTC(),
+			ExpiresAt: nullTimePtr(t.ExpiresAt), LastUsedAt: nullTimePtr(t.LastUsedAt)}
+		records = append(records, record)
+		out.Rows = append(out.Rows, []string{t.ID.String(), t.Name, record.CreatedAt.Format(time.RFC3339),
+			formatNullTimeRFC3339(t.ExpiresAt), formatNullTimeRFC3339(t.LastUsedAt)})
+	}
+	out.Records = records
+	return s.printListing(out, func() {
+		if len(tokens) == 0 {
+			fmt.Println("No API tokens - create one with: gator token create")
+			return
+		}
+		for _, t := range tokens {
+			fmt.Printf("%s  %-12s  created %s  expires %s  last used %s\n",
+				t.ID, t.Name,
+				t.CreatedAt.Format(time.RFC1123),
+				formatNullTime(t.ExpiresAt, "never"),
+				formatNullTime(t.LastUsedAt, "never"))
+		}
+	})
+}
+
+func handlerTokenRevoke(s *state, cmd command, user database.User) error {
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return usageError("invalid token id %q", cmd.args[0])
+	}
+	ctx := context.Background()
+	n, err := s.db.RevokeAPIToken(ctx, database.RevokeAPITokenParams{ID: id, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if n == 0 {
+		return notFoundError("token %s not found", id)
+	}
+	fmt.Printf("Token %s revoked\n", id)
+	return nil
+}
+
+func formatNullTime(t sql.NullTime, fallback string) string {
+	if !t.Valid {
+		return fallback
+	}
+	return t.Time.Format(time.RFC1123)
+}
+
+// nullTimePtr is t in UTC for a JSON record, or nil when t is NULL.
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
+
+func formatNullTimeRFC3339(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
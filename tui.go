@@ -0,0 +1,495 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"gator/internal/database"
+	"golang.org/x/term"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	tuiPostLimit    = 200
+	tuiSidebarWidth = 26
+	// Below this size there is no room for both panes.
+	tuiMinWidth  = tuiSidebarWidth + 21
+	tuiMinHeight = 8
+)
+
+// Terminal escape sequences used by the TUI.
+const (
+	escAltScreenOn  = "\x1b[?1049h"
+	escAltScreenOff = "\x1b[?1049l"
+	escHideCursor   = "\x1b[?25l"
+	escShowCursor   = "\x1b[?25h"
+	escClear        = "\x1b[2J"
+	escReverse      = "\x1b[7m"
+	escBold         = "\x1b[1m"
+	escDim          = "\x1b[2m"
+	escReset        = "\x1b[0m"
+)
+
+type tuiPane int
+
+const (
+	paneFeeds tuiPane = iota
+	panePosts
+)
+
+type tui struct {
+	s    *state
+	user database.User
+	ctx  context.Context
+
+	feeds      []database.Feed
+	unread     map[int64]int64
+	feedIdx    int // 0 is "All feeds", i > 0 is feeds[i-1]
+	feedScroll int
+
+	posts      []database.GetPostsWithStateForUserRow
+	postIdx    int
+	postScroll int
+	unreadOnly bool
+
+	focus  tuiPane
+	status string
+	width  int
+	height int
+}
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("tui needs an interactive terminal")
+	}
+	t := &tui{s: s, user: user, ctx: context.Background(), focus: panePosts}
+	if err := t.reload(); err != nil {
+		return fmt.Errorf("failed to load posts: %w", err)
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	fmt.Print(escAltScreenOn + escHideCursor)
+	defer func() {
+		fmt.Print(escShowCursor + escAltScreenOff)
+		term.Restore(fd, oldState)
+	}()
+
+	in := bufio.NewReader(os.Stdin)
+	for {
+		t.render()
+		key, err := readKey(in)
+		if err != nil {
+			return err
+		}
+		if quit := t.handleKey(key); quit {
+			return nil
+		}
+	}
+}
+
+// readKey returns a single keypress, mapping arrow-key escape sequences to
+// "up", "down", "left" and "right".
+func readKey(in *bufio.Reader) (string, error) {
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	if r != 0x1b {
+		return string(r), nil
+	}
+	if in.Buffered() < 2 {
+		return "esc", nil
+	}
+	b1, _ := in.ReadByte()
+	b2, _ := in.ReadByte()
+	if b1 != '[' {
+		return "esc", nil
+	}
+	switch b2 {
+	case 'A':
+		return "up", nil
+	case 'B':
+		return "down", nil
+	case 'C':
+		return "right", nil
+	case 'D':
+		return "left", nil
+	}
+	return "esc", nil
+}
+
+func (t *tui) handleKey(key string) bool {
+	t.status = ""
+	switch key {
+	case "q", "\x03":
+		return true
+	case "\t", "h", "l", "left", "right":
+		if t.focus == paneFeeds {
+			t.focus = panePosts
+		} else {
+			t.focus = paneFeeds
+		}
+	case "j", "down":
+		t.move(1)
+	case "k", "up":
+		t.move(-1)
+	case "\r", "o":
+		if t.focus == paneFeeds {
+			t.focus = panePosts
+			break
+		}
+		if post, ok := t.selectedPost(); ok {
+			if err := openBrowser(postLink(post.Url, post.OriginalUrl)); err != nil {
+				t.status = fmt.Sprintf("unable to open browser: %v", err)
+				break
+			}
+			t.setRead(true)
+		}
+	case "m":
+		if post, ok := t.selectedPost(); ok {
+			t.setRead(!post.IsRead)
+		}
+	case "s":
+		if post, ok := t.selectedPost(); ok {
+			change := "saved"
+			if post.IsStarred {
+				change = "unsaved"
+			}
+			if err := setPostState(t.ctx, t.s, t.user, post.ID, change); err != nil {
+				t.status = fmt.Sprintf("unable to star post: %v", err)
+				break
+			}
+			t.posts[t.postIdx].IsStarred = !post.IsStarred
+		}
+	case "u":
+		t.unreadOnly = !t.unreadOnly
+		t.reloadWithStatus()
+	case "r":
+		t.reloadWithStatus()
+		if t.status == "" {
+			t.status = fmt.Sprintf("refreshed at %s", time.Now().Format("15:04:05"))
+		}
+	}
+	return false
+}
+
+func (t *tui) move(delta int) {
+	if t.focus == paneFeeds {
+		next := t.feedIdx + delta
+		if next < 0 || next > len(t.feeds) {
+			return
+		}
+		t.feedIdx = next
+		t.postIdx, t.postScroll = 0, 0
+		t.reloadWithStatus()
+		return
+	}
+	next := t.postIdx + delta
+	if next >= 0 && next < len(t.posts) {
+		t.postIdx = next
+	}
+}
+
+func (t *tui) selectedPost() (database.GetPostsWithStateForUserRow, bool) {
+	if t.postIdx >= len(t.posts) {
+		return database.GetPostsWithStateForUserRow{}, false
+	}
+	return t.posts[t.postIdx], true
+}
+
+func (t *tui) setRead(read bool) {
+	post := t.posts[t.postIdx]
+	change := "unread"
+	if read {
+		change = "read"
+	}
+	if err := setPostState(t.ctx, t.s, t.user, post.ID, change); err != nil {
+		t.status = fmt.Sprintf("unable to mark post: %v", err)
+		return
+	}
+	if post.IsRead != read {
+		t.posts[t.postIdx].IsRead = read
+		if read {
+			t.unread[post.FeedSeq]--
+		} else {
+			t.unread[post.FeedSeq]++
+		}
+	}
+}
+
+func (t *tui) reloadWithStatus() {
+	if err := t.reload(); err != nil {
+		t.status = fmt.Sprintf("unable to load posts: %v", err)
+	}
+}
+
+// reload re-reads feeds, unread counts and the selected feed's posts.
+func (t *tui) reload() error {
+	feeds, err := t.s.db.GetFeedsForUser(t.ctx, t.user.ID)
+	if err != nil {
+		return err
+	}
+	counts, err := t.s.db.GetUnreadCountsForUser(t.ctx, t.user.ID)
+	if err != nil {
+		return err
+	}
+	t.feeds = feeds
+	t.feedIdx = min(t.feedIdx, len(feeds))
+	t.unread = map[int64]int64{}
+	for _, c := range counts {
+		t.unread[c.FeedSeq] = c.Unread
+	}
+
+	params := database.GetPostsWithStateForUserParams{
+		UserID:     t.user.ID,
+		UnreadOnly: t.unreadOnly,
+		MaxItems:   tuiPostLimit,
+	}
+	if t.feedIdx > 0 {
+		params.FeedSeq = t.feeds[t.feedIdx-1].Seq
+	}
+	posts, err := t.s.db.GetPostsWithStateForUser(t.ctx, params)
+	if err != nil {
+		return err
+	}
+	t.posts = posts
+	t.postIdx = min(t.postIdx, max(len(posts)-1, 0))
+	return nil
+}
+
+func (t *tui) render() {
+	t.width, t.height = 80, 24
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		t.width, t.height = w, h
+	}
+	fmt.Print(t.frame())
+}
+
+// frame draws the whole screen at t.width by t.height.
+func (t *tui) frame() string {
+	if t.width < tuiMinWidth || t.height < tuiMinHeight {
+		msg := fmt.Sprintf("Terminal too small (%dx%d, need %dx%d)", t.width, t.height, tuiMinWidth, tuiMinHeight)
+		return escClear + "\x1b[H" + truncate(msg, max(t.width, 0))
+	}
+	bodyHeight := t.height - 2
+	listHeight := max(bodyHeight/2, 3)
+	previewHeight := bodyHeight - listHeight - 1
+	mainWidth := t.width - tuiSidebarWidth - 1
+
+	sidebar := t.renderFeeds(bodyHeight)
+	list := t.renderPosts(listHeight, mainWidth)
+	preview := t.renderPreview(previewHeight, mainWidth)
+	right := append(list, escDim+strings.Repeat("─", mainWidth)+escReset)
+	right = append(right, preview...)
+
+	var b strings.Builder
+	b.WriteString(escClear + "\x1b[H")
+	filter := "all"
+	if t.unreadOnly {
+		filter = "unread"
+	}
+	b.WriteString(escReverse + pad(fmt.Sprintf(" gator · %s · %s posts", t.user.Name, filter), t.width) + escReset + "\r\n")
+	for i := 0; i < bodyHeight; i++ {
+		b.WriteString(sidebar[i])
+		b.WriteString(escDim + "│" + escReset)
+		if i < len(right) {
+			b.WriteString(right[i])
+		}
+		b.WriteString("\r\n")
+	}
+	help := " j/k move  tab switch pane  o open  m read  s star  u unread only  r refresh  q quit"
+	if t.status != "" {
+		help = " " + t.status
+	}
+	b.WriteString(escReverse + pad(help, t.width) + escReset)
+	return b.String()
+}
+
+func (t *tui) renderFeeds(height int) []string {
+	var total int64
+	for _, n := range t.unread {
+		total += n
+	}
+	names := []string{fmt.Sprintf("All feeds (%d)", total)}
+	for _, feed := range t.feeds {
+		names = append(names, fmt.Sprintf("%s (%d)", feed.Name, t.unread[feed.Seq]))
+	}
+	t.feedScroll = scrollTo(t.feedIdx, t.feedScroll, height)
+
+	lines := make([]string, height)
+	for i := range lines {
+		idx := t.feedScroll + i
+		if idx >= len(names) {
+			lines[i] = pad("", tuiSidebarWidth)
+			continue
+		}
+		line := pad(" "+names[idx], tuiSidebarWidth)
+		if idx == t.feedIdx {
+			if t.focus == paneFeeds {
+				line = escReverse + line + escReset
+			} else {
+				line = escBold + line + escReset
+			}
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+func (t *tui) renderPosts(height, width int) []string {
+	lines := make([]string, height)
+	if len(t.posts) == 0 {
+		lines[0] = pad(" No posts - try addfeed & agg first.", width)
+		for i := 1; i < height; i++ {
+			lines[i] = pad("", width)
+		}
+		return lines
+	}
+	t.postScroll = scrollTo(t.postIdx, t.postScroll, height)
+	for i := range lines {
+		idx := t.postScroll + i
+		if idx >= len(t.posts) {
+			lines[i] = pad("", width)
+			continue
+		}
+		post := t.posts[idx]
+		marker := " "
+		if !post.IsRead {
+			marker = "•"
+		}
+		if post.IsStarred {
+			marker = "★"
+		}
+		date := postRowTime(post).Format("Jan 02")
+		title := truncate(post.Title, max(width-len(date)-4, 1))
+		line := pad(fmt.Sprintf(" %s %s", marker, title), width-len(date)-1) + date + " "
+		switch {
+		case idx == t.postIdx && t.focus == panePosts:
+			line = escReverse + line + escReset
+		case idx == t.postIdx:
+			line = escBold + line + escReset
+		case post.IsRead:
+			line = escDim + line + escReset
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+func (t *tui) renderPreview(height, width int) []string {
+	post, ok := t.selectedPost()
+	if !ok || height <= 0 {
+		return nil
+	}
+	lines := []string{
+		escBold + truncate(" "+post.Title, width) + escReset,
+		escDim + truncate(fmt.Sprintf(" %s · %s", post.FeedName, postRowTime(post).Format(time.RFC1123)), width) + escReset,
+		escDim + truncate(" "+postLink(post.Url, post.OriginalUrl), width) + escReset,
+		"",
+	}
+	for _, line := range wrapText(termText(stripTags(postHTML(post.Description, post.Content))), width-2) {
+		lines = append(lines, " "+line)
+	}
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lines
+}
+
+// scrollTo returns the scroll offset that keeps idx visible in a window of
+// the given height.
+func scrollTo(idx, scroll, height int) int {
+	if idx < scroll {
+		return idx
+	}
+	if idx >= scroll+height {
+		return idx - height + 1
+	}
+	return scroll
+}
+
+// termText replaces control characters with spaces, so that feed titles
+// and content can't move the cursor or change the terminal's state with
+// escape sequences of their own.
+func termText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// truncate shortens s to width runes. Titles, names and links are drawn
+// through it, so it also strips control characters.
+func truncate(s string, width int) string {
+	r := []rune(termText(s))
+	if width <= 0 {
+		return ""
+	}
+	if len(r) <= width {
+		return string(r)
+	}
+	if width == 1 {
+		return string(r[:1])
+	}
+	return string(r[:width-1]) + "…"
+}
+
+func pad(s string, width int) string {
+	s = truncate(s, width)
+	if n := len([]rune(s)); n < width {
+		s += strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// wrapText breaks s into lines of at most width runes on word boundaries.
+func wrapText(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		if len(line) > 0 && len(line)+1+len(w) > width {
+			lines = append(lines, string(line))
+			line = line[:0]
+		}
+		for len(w) > width {
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
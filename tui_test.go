@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"gator/internal/database"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+)
+
+func testTUI() *tui {
+	now := time.Now()
+	evil := "Hello\x1b]0;pwned\x07\x1b[2J world\u009b31m"
+	return &tui{
+		user:   database.User{Name: "alice"},
+		feeds:  []database.Feed{{Name: "Feed " + evil, Seq: 1}},
+		unread: map[int64]int64{1: 2},
+		posts: []database.GetPostsWithStateForUserRow{
+			{Title: evil, Url: "https://example.com/\x1b[1m", FeedName: evil, FeedSeq: 1, CreatedAt: now,
+				Description: sql.NullString{String: "<p>Body " + evil + "</p>", Valid: true}},
+			{Title: "Second", FeedSeq: 1, CreatedAt: now, IsRead: true},
+		},
+		focus: panePosts,
+	}
+}
+
+func TestTUIFrameAnySize(t *testing.T) {
+	tu := testTUI()
+	for width := -1; width <= tuiMinWidth+10; width++ {
+		for height := -1; height <= tuiMinHeight+10; height++ {
+			tu.width, tu.height = width, height
+			frame := tu.frame()
+			if width < tuiMinWidth || height < tuiMinHeight {
+				if !strings.Contains(frame, "too small") && width > len("Terminal too small") {
+					t.Errorf("%dx%d: no too-small message: %q", width, height, frame)
+				}
+				continue
+			}
+			if got := strings.Count(frame, "\r\n"); got != height-1 {
+				t.Errorf("%dx%d: %d lines, want %d", width, height, got+1, height)
+			}
+		}
+	}
+}
+
+func TestTUIStripsControlCharacters(t *testing.T) {
+	tu := testTUI()
+	tu.width, tu.height = 120, 40
+	frame := tu.frame()
+	// Whatever is left once the TUI's own sequences are removed came from
+	// the feed and must be free of control characters.
+	rest := strings.NewReplacer(escClear+"\x1b[H", "", escReverse, "", escBold, "", escDim, "", escReset, "",
+		"\r\n", "").Replace(frame)
+	for _, r := range rest {
+		if unicode.IsControl(r) {
+			t.Fatalf("frame contains control character %q: %q", r, rest)
+		}
+	}
+	if !strings.Contains(rest, "Hello ]0;pwned  [2J world 31m") {
+		t.Errorf("title not drawn with control characters replaced: %q", rest)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 4, "hel…"},
+		{"hello", 1, "h"},
+		{"hello", 0, ""},
+		{"hello", -3, ""},
+		{"héllo wörld", 6, "héllo…"},
+		{"a\tb\x1b[0mc", 10, "a b [0mc"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+	if got := pad("ab", -1); got != "" {
+		t.Errorf("pad with negative width = %q", got)
+	}
+}
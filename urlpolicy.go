@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gator/internal/config"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// nonPublicPrefixes are special-purpose ranges beyond those netip.Addr
+// already classifies (loopback, private, link-local, multicast).
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, embeds an IPv4 address
+	netip.MustParsePrefix("2002::/16"),    // 6to4, likewise
+}
+
+// urlPolicy stops users of a shared gator from making it fetch anything but
+// public http(s) servers: cloud metadata endpoints, the database, admin
+// interfaces on the local network. Addresses are checked after DNS
+// resolution, on every connection, so neither redirects nor DNS tricks get
+// around it. Hosts and networks on the allow-lists are exempt.
+type urlPolicy struct {
+	allowHosts    map[string]bool
+	allowNetworks []netip.Prefix
+
+	// proxies are the proxy servers requests were routed through; dialing
+	// them is always allowed.
+	proxies sync.Map
+}
+
+func newURLPolicy(cfg *config.HTTPConfig) (*urlPolicy, error) {
+	p := &urlPolicy{allowHosts: map[string]bool{}}
+	for _, host := range cfg.AllowHosts {
+		p.allowHosts[strings.ToLower(host)] = true
+	}
+	for _, network := range cfg.AllowNetworks {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http.allow_networks entry %q (use CIDR notation such as 10.0.0.0/8)", network)
+		}
+		p.allowNetworks = append(p.allowNetworks, prefix.Masked())
+	}
+	return p, nil
+}
+
+// checkURL rejects schemes other than http and https.
+func (p *urlPolicy) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("refusing to fetch %s: only http and https URLs are allowed", u.Redacted())
+	}
+	return nil
+}
+
+func (p *urlPolicy) allowedAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, prefix := range p.allowNetworks {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve returns the addresses of host that may be connected to, or an
+// error if there are none.
+func (p *urlPolicy) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	var allowed []netip.Addr
+	for _, addr := range addrs {
+		if p.allowedAddr(addr) {
+			allowed = append(allowed, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	if len(allowed) == 0 {
+		if ip := addrs[0].Unmap().String(); ip != host {
+			host += " (" + ip + ")"
+		}
+		return nil, notPublicError(host)
+	}
+	return allowed, nil
+}
+
+func notPublicError(host string) error {
+	return fmt.Errorf("refusing to connect to %s: not a public address - add it to http.allow_hosts or http.allow_networks if it is a trusted feed", host)
+}
+
+// dialContext connects only to allowed addresses.
+func (p *urlPolicy) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.proxies.Load(strings.ToLower(addr)); ok || p.allowHosts[strings.ToLower(host)] {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		addrs, err := p.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var conn net.Conn
+		for _, ip := range addrs {
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// proxy wraps the transport's proxy selection. A proxied request never
+// reaches dialContext with its own host, and resolving that host here could
+// give a different answer than the proxy gets, so only literal addresses are
+// checked; names are left to the proxy. The proxy itself is let through.
+func (p *urlPolicy) proxy(next func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := next(req)
+		if err != nil || proxyURL == nil {
+			return proxyURL, err
+		}
+		host := strings.ToLower(req.URL.Hostname())
+		if ip, err := netip.ParseAddr(host); err == nil && !p.allowHosts[host] && !p.allowedAddr(ip.WithZone("")) {
+			return nil, notPublicError(host)
+		}
+		port := proxyURL.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxyURL.Scheme]
+		}
+		p.proxies.Store(strings.ToLower(net.JoinHostPort(proxyURL.Hostname(), port)), true)
+		return proxyURL, nil
+	}
+}
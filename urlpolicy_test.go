@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"gator/internal/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestPolicy(t *testing.T, cfg config.HTTPConfig) *urlPolicy {
+	t.Helper()
+	p, err := newURLPolicy(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestURLPolicyAllowedAddr(t *testing.T) {
+	p := newTestPolicy(t, config.HTTPConfig{AllowNetworks: []string{"10.20.0.0/16", "64:ff9b::a14:0/120"}})
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // cloud metadata
+		{"100.64.0.1", false},      // carrier-grade NAT
+		{"0.0.0.0", false},
+		{"::", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},   // IPv4-mapped
+		{"64:ff9b::7f00:1", false},    // NAT64 for 127.0.0.1
+		{"64:ff9b::a9fe:a9fe", false}, // NAT64 for 169.254.169.254
+		{"2002:7f00:1::1", false},     // 6to4 for 127.0.0.1
+		{"10.20.3.4", true},           // allow_networks
+		{"::ffff:10.20.3.4", true},    // allow_networks, mapped
+		{"64:ff9b::a14:5", true},      // allow_networks beats the NAT64 block
+		{"10.21.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := p.allowedAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("allowedAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestURLPolicyConfig(t *testing.T) {
+	if _, err := newURLPolicy(&config.HTTPConfig{AllowNetworks: []string{"10.0.0.1"}}); err == nil {
+		t.Error("allow_networks entry without a prefix length accepted")
+	}
+	p := newTestPolicy(t, config.HTTPConfig{AllowNetworks: []string{"10.20.3.4/16"}})
+	if !p.allowedAddr(netip.MustParseAddr("10.20.200.1")) {
+		t.Error("allow_networks entry with host bits set isn't masked")
+	}
+}
+
+func TestURLPolicyCheckURL(t *testing.T) {
+	p := newTestPolicy(t, config.HTTPConfig{})
+	for rawURL, ok := range map[string]bool{
+		"http://example.com/feed":  true,
+		"https://example.com/feed": true,
+		"file:///etc/passwd":       false,
+		"gopher://example.com/":    false,
+		"ftp://example.com/feed":   false,
+	} {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.checkURL(u); (err == nil) != ok {
+			t.Errorf("checkURL(%s) = %v", rawURL, err)
+		}
+	}
+}
+
+func TestURLPolicyDial(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	ctx := context.Background()
+
+	dial := newTestPolicy(t, config.HTTPConfig{}).dialContext(&net.Dialer{})
+	for _, host := range []string{"127.0.0.1", "localhost"} {
+		if _, err := dial(ctx, "tcp", net.JoinHostPort(host, port)); err == nil || !strings.Contains(err.Error(), "not a public address") {
+			t.Errorf("dial %s: %v", host, err)
+		}
+	}
+	for _, cfg := range []config.HTTPConfig{
+		{AllowHosts: []string{"LocalHost"}},
+		{AllowNetworks: []string{"127.0.0.0/8", "::1/128"}},
+	} {
+		conn, err := newTestPolicy(t, cfg).dialContext(&net.Dialer{})(ctx, "tcp", net.JoinHostPort("localhost", port))
+		if err != nil {
+			t.Errorf("dial with %+v: %v", cfg, err)
+			continue
+		}
+		conn.Close()
+	}
+}
+
+func TestURLPolicyProxy(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.corp.example:3128")
+	p := newTestPolicy(t, config.HTTPConfig{AllowHosts: []string{"10.1.2.3"}})
+	proxy := p.proxy(func(*http.Request) (*url.URL, error) { return proxyURL, nil })
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		// Names are the proxy's to resolve: .invalid never resolves, so
+		// these would fail if they were looked up here.
+		{"http://feeds.invalid/rss", true},
+		{"https://internal.invalid:8443/rss", true},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://[::1]:8080/", false},
+		{"http://[64:ff9b::a9fe:a9fe]/", false},
+		{"http://[fe80::1%25eth0]/", false},
+		{"http://10.1.2.3/rss", true}, // allow_hosts
+		{"http://93.184.215.14/rss", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		got, err := proxy(req)
+		if tt.ok && (err != nil || got != proxyURL) {
+			t.Errorf("%s: got %v, %v; want the proxy", tt.url, got, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: handed to the proxy", tt.url)
+		}
+	}
+
+	if _, ok := p.proxies.Load("proxy.corp.example:3128"); !ok {
+		t.Error("proxy address not let through dialContext")
+	}
+	proxyURL, _ = url.Parse("http://Proxy2.corp.example")
+	proxy(httptest.NewRequest(http.MethodGet, "http://feeds.invalid/", nil))
+	if _, ok := p.proxies.Load("proxy2.corp.example:80"); !ok {
+		t.Error("proxy without a port not recorded on the scheme's default port")
+	}
+
+	// Requests the proxy config bypasses are left to dialContext.
+	direct := p.proxy(func(*http.Request) (*url.URL, error) { return nil, nil })
+	if got, err := direct(httptest.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)); got != nil || err != nil {
+		t.Errorf("unproxied request: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"html"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed web
+var webFS embed.FS
+
+const (
+	sessionCookie   = "gator_session"
+	sessionLifetime = 30 * 24 * time.Hour
+	webPageSize     = 30
+	excerptLength   = 280
+)
+
+var webTemplates = map[string]*template.Template{}
+
+func init() {
+	funcs := template.FuncMap{
+		"date": func(t time.Time) string { return t.Format("Jan 2, 15:04") },
+	}
+	for _, page := range []string{"timeline", "subscriptions", "login"} {
+		webTemplates[page] = template.Must(template.New("layout.html").Funcs(funcs).ParseFS(webFS,
+			"web/templates/layout.html", "web/templates/"+page+".html"))
+	}
+}
+
+type webPost struct {
+	Seq       int64
+	Title     string
+	URL       string
+	FeedSeq   int64
+	FeedName  string
+	Published time.Time
+	Excerpt   string
+	IsRead    bool
+	IsStarred bool
+}
+
+type webFeed struct {
+	Seq       int64
+	Name      string
+	URL       string
+	Following bool
+}
+
+type webPage struct {
+	User    database.User
+	Title   string
+	Error   string
+	Posts   []webPost
+	Feeds   []webFeed
+	Feed    *webFeed
+	Unread  bool
+	Starred bool
+	Older   string
+}
+
+func renderPage(w http.ResponseWriter, name string, data webPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := webTemplates[name].Execute(w, data); err != nil {
+		log.Printf("Failed to render %s: %+v", name, err)
+	}
+}
+
+// stripTags turns a feed's HTML description into a short plain-text excerpt.
+// Feed HTML is never rendered as-is in the UI.
+func stripTags(s string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+			b.WriteByte(' ')
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(b.String())), " ")
+}
+
+func excerpt(s string, n int) string {
+	s = stripTags(s)
+	if r := []rune(s); len(r) > n {
+		return strings.TrimSpace(string(r[:n])) + "…"
+	}
+	return s
+}
+
+// middlewareWebSession is the browser counterpart of middlewareAuthenticated:
+// the API token lives in a cookie, and unauthenticated visitors are sent to
+// the login page.
+func middlewareWebSession(s *state, handler authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		user, err := authenticateToken(r.Context(), s, cookie.Value, tokenKindSession)
+		if err != nil {
+			if !errors.Is(err, errInvalidToken) && !errors.Is(err, errTokenExpired) {
+				log.Printf("Failed to look up session: %+v", err)
+			}
+			http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+func webHandlerLoginForm(w http.ResponseWriter, r *http.Request) {
+	renderPage(w, "login", webPage{Title: "Log in"})
+}
+
+// webHandlerLogin checks the user's password (see `gator passwd`) and starts
+// a session backed by a session token. The user's expired sessions are
+// deleted on the way.
+func webHandlerLogin(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		name := r.PostFormValue("name")
+		user, err := s.db.GetUser(ctx, name)
+		if err == nil {
+			err = checkPassword(user, r.PostFormValue("password"))
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			renderPage(w, "login", webPage{Title: "Log in", Error: "Unknown user or wrong password. Users need a password (gator passwd) to use the web UI."})
+			return
+		}
+
+		err = s.db.DeleteExpiredSessions(ctx, database.DeleteExpiredSessionsParams{UserID: user.ID, Now: time.Now().UTC()})
+		if err != nil {
+			log.Printf("Failed to delete expired sessions: %+v", err)
+		}
+		token, err := newAPIToken()
+		if err != nil {
+			http.Error(w, "unable to start session", http.StatusInternalServerError)
+			return
+		}
+		now := time.Now().UTC()
+		_, err = s.db.CreateAPIToken(ctx, database.CreateAPITokenParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    user.ID,
+			Name:      "web session",
+			TokenHash: hashAPIToken(token),
+			ExpiresAt: sql.NullTime{Time: now.Add(sessionLifetime), Valid: true},
+			Kind:      tokenKindSession,
+		})
+		if err != nil {
+			log.Printf("Failed to create session token: %+v", err)
+			http.Error(w, "unable to start session", http.StatusInternalServerError)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    token,
+			Path:     "/",
+			Expires:  now.Add(sessionLifetime),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+func webHandlerLogout(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			row, err := s.db.GetUserByAPIToken(r.Context(), hashAPIToken(cookie.Value))
+			if err == nil {
+				s.db.RevokeAPIToken(r.Context(), database.RevokeAPITokenParams{ID: row.TokenID, UserID: row.ID})
+			}
+		}
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	}
+}
+
+// webHandlerTimeline serves both the full timeline (/) and a single feed's
+// posts (/feeds/{seq}); ?unread=1 and ?starred=1 filter, ?before= pages.
+func webHandlerTimeline(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		ctx := r.Context()
+		page := webPage{
+			User:    user,
+			Title:   "Timeline",
+			Unread:  r.FormValue("unread") == "1",
+			Starred: r.FormValue("starred") == "1",
+		}
+		params := database.GetPostsWithStateForUserParams{
+			UserID:      user.ID,
+			UnreadOnly:  page.Unread,
+			StarredOnly: page.Starred,
+			MaxItems:    webPageSize,
+		}
+		params.MaxSeq, _ = strconv.ParseInt(r.FormValue("before"), 10, 64)
+
+		if seqStr := r.PathValue("seq"); seqStr != "" {
+			seq, err := strconv.ParseInt(seqStr, 10, 64)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			feed, err := s.db.GetFeedBySeq(ctx, seq)
+			if errors.Is(err, sql.ErrNoRows) {
+				http.NotFound(w, r)
+				return
+			} else if err != nil {
+				log.Printf("Failed to get feed: %+v", err)
+				http.Error(w, "unable to load feed", http.StatusInternalServerError)
+				return
+			}
+			params.FeedSeq = feed.Seq
+			page.Feed = &webFeed{Seq: feed.Seq, Name: feed.Name, URL: feed.Url}
+			page.Title = feed.Name
+		}
+
+		rows, err := s.db.GetPostsWithStateForUser(ctx, params)
+		if err != nil {
+			log.Printf("Failed to get posts: %+v", err)
+			http.Error(w, "unable to load posts", http.StatusInternalServerError)
+			return
+		}
+		for _, row := range rows {
+			page.Posts = append(page.Posts, webPost{
+				Seq:       row.Seq,
+				Title:     row.Title,
+				URL:       postLink(row.Url, row.OriginalUrl),
+				FeedSeq:   row.FeedSeq,
+				FeedName:  row.FeedName,
+				Published: postRowTime(row),
+				Excerpt:   excerpt(row.Description.String, excerptLength),
+				IsRead:    row.IsRead,
+				IsStarred: row.IsStarred,
+			})
+		}
+		if len(rows) == webPageSize {
+			q := r.URL.Query()
+			q.Set("before", strconv.FormatInt(rows[len(rows)-1].Seq, 10))
+			page.Older = r.URL.Path + "?" + q.Encode()
+		}
+		renderPage(w, "timeline", page)
+	}
+}
+
+func webHandlerSubscriptions(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		page, err := subscriptionsPage(r.Context(), s, user)
+		if err != nil {
+			log.Printf("Failed to get feeds: %+v", err)
+			http.Error(w, "unable to load feeds", http.StatusInternalServerError)
+			return
+		}
+		renderPage(w, "subscriptions", page)
+	}
+}
+
+func subscriptionsPage(ctx context.Context, s *state, user database.User) (webPage, error) {
+	page := webPage{User: user, Title: "Subscriptions"}
+	following, err := s.db.GetFeedsForUser(ctx, user.ID)
+	if err != nil {
+		return page, err
+	}
+	followed := map[int64]bool{}
+	for _, feed := range following {
+		followed[feed.Seq] = true
+		page.Feeds = append(page.Feeds, webFeed{Seq: feed.Seq, Name: feed.Name, URL: feed.Url, Following: true})
+	}
+	all, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		return page, err
+	}
+	for _, feed := range all {
+		if followed[feed.Seq] {
+			continue
+		}
+		page.Feeds = append(page.Feeds, webFeed{Seq: feed.Seq, Name: feed.Name, URL: feed.Url})
+	}
+	return page, nil
+}
+
+func webHandlerAddSubscription(s *state) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		ctx := r.Context()
+		feedURL := strings.TrimSpace(r.PostFormValue("url"))
+		_, err := followFeedURL(ctx, s, user, feedURL, strings.TrimSpace(r.PostFormValue("name")))
+		if err != nil {
+			page, perr := subscriptionsPage(ctx, s, user)
+			if perr != nil {
+				http.Error(w, "unable to load feeds", http.StatusInternalServerError)
+				return
+			}
+			page.Error = err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			renderPage(w, "subscriptions", page)
+			return
+		}
+		http.Redirect(w, r, "/subscriptions", http.StatusSeeOther)
+	}
+}
+
+// webHandlerFollow handles POST /subscriptions/{seq}/follow and /unfollow.
+func webHandlerFollow(s *state, follow bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		ctx := r.Context()
+		seq, _ := strconv.ParseInt(r.PathValue("seq"), 10, 64)
+		feed, err := s.db.GetFeedBySeq(ctx, seq)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if follow {
+			_, err = followFeedURL(ctx, s, user, feed.Url, "")
+		} else {
+			err = s.db.UnFollow(ctx, database.UnFollowParams{UserID: user.ID, FeedID: feed.ID})
+		}
+		if err != nil {
+			log.Printf("Failed to update follow: %+v", err)
+			http.Error(w, "unable to update subscription", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/subscriptions", http.StatusSeeOther)
+	}
+}
+
+// webHandlerPostState handles POST /posts/{seq}/{read,unread,star,unstar}.
+// Requests from app.js get 204; plain form posts are redirected back.
+func webHandlerPostState(s *state) authedHandler {
+	changes := map[string]string{"read": "read", "unread": "unread", "star": "saved", "unstar": "unsaved"}
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		ctx := r.Context()
+		change, ok := changes[r.PathValue("action")]
+		seq, err := strconv.ParseInt(r.PathValue("seq"), 10, 64)
+		if !ok || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		posts, err := s.db.GetPostsWithStateBySeq(ctx, database.GetPostsWithStateBySeqParams{UserID: user.ID, Seqs: []int64{seq}})
+		if err != nil || len(posts) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		if err := setPostState(ctx, s, user, posts[0].ID, change); err != nil {
+			log.Printf("Failed to update post state: %+v", err)
+			http.Error(w, "unable to update post", http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("X-Requested-With") == "fetch" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		back := r.Referer()
+		if back == "" {
+			back = "/"
+		}
+		http.Redirect(w, r, back, http.StatusSeeOther)
+	}
+}
+
+func registerWebRoutes(s *state, mux *http.ServeMux) {
+	static, _ := fs.Sub(webFS, "web/static")
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+
+	mux.HandleFunc("GET /login", webHandlerLoginForm)
+	mux.Handle("POST /login", webHandlerLogin(s))
+	mux.Handle("POST /logout", webHandlerLogout(s))
+
+	mux.Handle("GET /{$}", middlewareWebSession(s, webHandlerTimeline(s)))
+	mux.Handle("GET /feeds/{seq}", middlewareWebSession(s, webHandlerTimeline(s)))
+	mux.Handle("GET /subscriptions", middlewareWebSession(s, webHandlerSubscriptions(s)))
+	mux.Handle("POST /subscriptions", middlewareWebSession(s, webHandlerAddSubscription(s)))
+	mux.Handle("POST /subscriptions/{seq}/follow", middlewareWebSession(s, webHandlerFollow(s, true)))
+	mux.Handle("POST /subscriptions/{seq}/unfollow", middlewareWebSession(s, webHandlerFollow(s, false)))
+	mux.Handle("POST /posts/{seq}/{action}", middlewareWebSession(s, webHandlerPostState(s)))
+}
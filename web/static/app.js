@@ -0,0 +1,91 @@
+// Keyboard shortcuts for the timeline: j/k move, o opens, m toggles read,
+// s toggles star. Everything also works without JavaScript via the forms.
+(function () {
+  "use strict";
+
+  var posts = Array.prototype.slice.call(document.querySelectorAll(".post"));
+  var current = -1;
+
+  function select(i) {
+    if (i < 0 || i >= posts.length) {
+      return;
+    }
+    if (current >= 0) {
+      posts[current].classList.remove("selected");
+    }
+    current = i;
+    posts[current].classList.add("selected");
+    posts[current].scrollIntoView({ block: "nearest" });
+  }
+
+  function post(url) {
+    return fetch(url, {
+      method: "POST",
+      credentials: "same-origin",
+      headers: { "X-Requested-With": "fetch" },
+    });
+  }
+
+  function setRead(el, read) {
+    var seq = el.dataset.seq;
+    return post("/posts/" + seq + "/" + (read ? "read" : "unread")).then(function (res) {
+      if (res.ok) {
+        el.classList.toggle("read", read);
+        el.querySelector(".toggle-read").textContent = read ? "Mark unread" : "Mark read";
+        el.querySelector(".toggle-read").form.action = "/posts/" + seq + "/" + (read ? "unread" : "read");
+      }
+    });
+  }
+
+  function setStarred(el, starred) {
+    var seq = el.dataset.seq;
+    return post("/posts/" + seq + "/" + (starred ? "star" : "unstar")).then(function (res) {
+      if (res.ok) {
+        el.classList.toggle("starred", starred);
+        el.querySelector(".toggle-star").textContent = starred ? "Unstar" : "Star";
+        el.querySelector(".toggle-star").form.action = "/posts/" + seq + "/" + (starred ? "unstar" : "star");
+      }
+    });
+  }
+
+  document.addEventListener("keydown", function (e) {
+    if (e.ctrlKey || e.metaKey || e.altKey || /input|textarea|select/i.test(e.target.tagName)) {
+      return;
+    }
+    var el = posts[current];
+    switch (e.key) {
+      case "j":
+        select(current + 1);
+        break;
+      case "k":
+        select(current - 1);
+        break;
+      case "o":
+        if (el) {
+          window.open(el.querySelector(".title").href, "_blank", "noopener");
+          setRead(el, true);
+        }
+        break;
+      case "m":
+        if (el) {
+          setRead(el, !el.classList.contains("read"));
+        }
+        break;
+      case "s":
+        if (el) {
+          setStarred(el, !el.classList.contains("starred"));
+        }
+        break;
+      default:
+        return;
+    }
+    e.preventDefault();
+  });
+
+  posts.forEach(function (el, i) {
+    el.querySelector(".title").addEventListener("click", function () {
+      select(i);
+      setRead(el, true);
+    });
+  });
+})();
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"github.com/google/uuid"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// websubLease is the subscription lifetime asked of hubs; they may
+	// grant less. Subscriptions are renewed a day before they lapse, and
+	// ones the hub denied are asked for again a day later.
+	websubLease = 10 * 24 * time.Hour
+	// websubInterval is how often serve looks for subscriptions to make or
+	// renew.
+	websubInterval = 5 * time.Minute
+)
+
+// websubLinks returns the hub a feed advertises and the topic URL to
+// subscribe to: its rel="self" link, or failing that the URL it was fetched
+// from.
+func websubLinks(feed *RSSFeed, feedURL string) (hub, topic string) {
+	topic = feedURL
+	for _, link := range feed.Channel.AtomLinks {
+		switch strings.ToLower(link.Rel) {
+		case "hub":
+			if hub == "" {
+				hub = strings.TrimSpace(link.Href)
+			}
+		case "self":
+			if href := strings.TrimSpace(link.Href); href != "" {
+				topic = href
+			}
+		}
+	}
+	return hub, topic
+}
+
+// recordWebSubHub notes the hub of a feed that advertises one, for serve to
+// subscribe to.
+func recordWebSubHub(ctx context.Context, s *state, dbFeed database.Feed, feed *RSSFeed) error {
+	hub, topic := websubLinks(feed, dbFeed.Url)
+	if hub == "" {
+		return nil
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	return s.db.UpsertWebSubHub(ctx, database.UpsertWebSubHubParams{
+		FeedID:   dbFeed.ID,
+		HubUrl:   hub,
+		TopicUrl: topic,
+		Secret:   hex.EncodeToString(secret),
+	})
+}
+
+// runWebSub subscribes to hubs, and renews subscriptions about to lapse,
+// until ctx is done. Hubs call back at publicURL + "/websub/<feed ID>".
+func runWebSub(ctx context.Context, s *state, publicURL string) {
+	ticker := time.NewTicker(websubInterval)
+	defer ticker.Stop()
+	for {
+		subs, err := s.db.GetWebSubSubscriptionsDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			fmt.Printf("Failed to get WebSub subscriptions: %+v\n", err)
+		}
+		for _, sub := range subs {
+			if err := requestWebSub(ctx, s, publicURL, sub); err != nil {
+				fmt.Printf("Failed to subscribe to %s at %s: %+v\n", sub.TopicUrl, sub.HubUrl, err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// requestWebSub asks the hub for a subscription. The hub confirms it
+// asynchronously by calling apiHandlerWebSubVerify.
+func requestWebSub(ctx context.Context, s *state, publicURL string, sub database.WebsubSubscription) error {
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {sub.TopicUrl},
+		"hub.callback":      {strings.TrimRight(publicURL, "/") + "/websub/" + sub.FeedID.String()},
+		"hub.secret":        {sub.Secret},
+		"hub.lease_seconds": {strconv.Itoa(int(websubLease.Seconds()))},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.HubUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := s.http.send(s.http.client, req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return &httpStatusError{URL: sub.HubUrl, StatusCode: res.StatusCode, Status: res.Status}
+	}
+	return s.db.MarkWebSubRequested(ctx, sub.FeedID)
+}
+
+func webSubSubscription(r *http.Request, s *state) (database.WebsubSubscription, bool) {
+	feedID, err := uuid.Parse(r.PathValue("feed"))
+	if err != nil {
+		return database.WebsubSubscription{}, false
+	}
+	sub, err := s.db.GetWebSubSubscription(r.Context(), feedID)
+	return sub, err == nil
+}
+
+// apiHandlerWebSubVerify answers the hub's verification of intent: the
+// challenge is echoed only for a subscription gator asked for in the last
+// hour, once per request, so a verification can't be replayed later to
+// stretch the lease. Leases longer than gator asked for are cut to
+// websubLease.
+func apiHandlerWebSubVerify(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, ok := webSubSubscription(r, s)
+		query := r.URL.Query()
+		if !ok || query.Get("hub.topic") != sub.TopicUrl {
+			http.NotFound(w, r)
+			return
+		}
+		switch query.Get("hub.mode") {
+		case "subscribe":
+			lease := websubLease
+			if seconds, err := strconv.Atoi(query.Get("hub.lease_seconds")); err == nil && seconds > 0 && seconds < int(websubLease.Seconds()) {
+				lease = time.Duration(seconds) * time.Second
+			}
+			n, err := s.db.ActivateWebSub(r.Context(), database.ActivateWebSubParams{
+				FeedID:         sub.FeedID,
+				LeaseExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(lease), Valid: true},
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "failed to record subscription")
+				return
+			}
+			if n == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, query.Get("hub.challenge"))
+		case "denied":
+			if err := s.db.DenyWebSub(r.Context(), sub.FeedID); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "failed to record denial")
+				return
+			}
+			fmt.Printf("Hub denied the subscription to %s: %s\n", sub.TopicUrl, query.Get("hub.reason"))
+			w.WriteHeader(http.StatusOK)
+		default:
+			// gator never unsubscribes; anyone asking to is not the hub.
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// apiHandlerWebSubPush ingests content pushed by the hub. Content whose
+// signature doesn't match is acknowledged but dropped, as WebSub requires.
+func apiHandlerWebSubPush(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, ok := webSubSubscription(r, s)
+		if !ok || sub.State != "active" {
+			http.NotFound(w, r)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, s.http.maxBodySize+1))
+		if err != nil || int64(len(body)) > s.http.maxBodySize {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "body too large")
+			return
+		}
+		if err := checkHubSignature(r.Header.Get("X-Hub-Signature"), sub.Secret, body); err != nil {
+			fmt.Printf("Ignoring WebSub push for %s: %v\n", sub.TopicUrl, err)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+		feed, err := parseFeed(body, r.Header.Get("Content-Type"))
+		if err != nil {
+			fmt.Printf("Failed to parse WebSub push for %s: %+v\n", sub.TopicUrl, err)
+			return
+		}
+		dbFeed, err := s.db.GetFeedByID(r.Context(), sub.FeedID)
+		if err != nil {
+			fmt.Printf("Failed to get feed for WebSub push: %+v\n", err)
+			return
+		}
+		fmt.Printf("\n[%s] (pushed by %s)\n", dbFeed.Name, sub.HubUrl)
+		storeFeedItems(context.WithoutCancel(r.Context()), s, dbFeed, feed)
+	}
+}
+
+// checkHubSignature verifies X-Hub-Signature, "<algorithm>=<hex HMAC>" of
+// the body keyed with the subscription's secret.
+func checkHubSignature(header, secret string, body []byte) error {
+	algorithm, signature, ok := strings.Cut(header, "=")
+	if !ok {
+		return errors.New("missing or malformed X-Hub-Signature")
+	}
+	var newHash func() hash.Hash
+	switch strings.ToLower(algorithm) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", algorithm)
+	}
+	want, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.New("malformed X-Hub-Signature")
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), want) {
+		return errors.New("X-Hub-Signature does not match")
+	}
+	return nil
+}
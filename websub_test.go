@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"gator/internal/config"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hubSignature(algorithm string, newHash func() hash.Hash, secret, body string) string {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(body))
+	return algorithm + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestCheckHubSignature(t *testing.T) {
+	const secret, body = "s3cret", "<rss/>"
+	tests := []struct {
+		name   string
+		header string
+		ok     bool
+	}{
+		{"sha1", hubSignature("sha1", sha1.New, secret, body), true},
+		{"sha256", hubSignature("sha256", sha256.New, secret, body), true},
+		{"sha384", hubSignature("sha384", sha512.New384, secret, body), true},
+		{"sha512", hubSignature("sha512", sha512.New, secret, body), true},
+		{"algorithm in capitals", hubSignature("SHA256", sha256.New, secret, body), true},
+		{"wrong secret", hubSignature("sha256", sha256.New, "guess", body), false},
+		{"other body", hubSignature("sha256", sha256.New, secret, "<rss></rss>"), false},
+		{"algorithm mismatch", "sha512=" + strings.TrimPrefix(hubSignature("sha256", sha256.New, secret, body), "sha256="), false},
+		{"unsupported algorithm", "md5=d41d8cd98f00b204e9800998ecf8427e", false},
+		{"not hex", "sha256=zz", false},
+		{"no algorithm", "d41d8cd98f00b204e9800998ecf8427e", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		if err := checkHubSignature(tt.header, secret, []byte(body)); (err == nil) != tt.ok {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func newWebSubFixture(t *testing.T) (*memStore, *state, http.Handler) {
+	db := newMemStore()
+	s := newTestState(db)
+	s.http = newTestHTTPClient(t, config.HTTPConfig{})
+	mux := http.NewServeMux()
+	mux.Handle("GET /websub/{feed}", apiHandlerWebSubVerify(s))
+	mux.Handle("POST /websub/{feed}", apiHandlerWebSubPush(s))
+	return db, s, mux
+}
+
+func verifyPath(feedID, mode, topic string, leaseSeconds int) string {
+	return "/websub/" + feedID + "?hub.mode=" + mode + "&hub.topic=" + topic +
+		"&hub.challenge=c0ffee&hub.lease_seconds=" + strconv.Itoa(leaseSeconds)
+}
+
+func TestWebSubVerify(t *testing.T) {
+	db, _, handler := newWebSubFixture(t)
+	alice := db.addUser("alice")
+	fresh := db.addFeed(alice, "Fresh", "https://fresh.example/rss")
+	stale := db.addFeed(alice, "Stale", "https://stale.example/rss")
+	unasked := db.addFeed(alice, "Unasked", "https://unasked.example/rss")
+	db.addWebSub(fresh, "https://hub.example/", "s", "pending", time.Now())
+	db.addWebSub(stale, "https://hub.example/", "s", "pending", time.Now().Add(-2*time.Hour))
+	db.addWebSub(unasked, "https://hub.example/", "s", "new", time.Time{})
+	id := fresh.ID.String()
+
+	replay(t, handler, []replayStep{
+		{name: "wrong topic", method: "GET", path: verifyPath(id, "subscribe", "https://evil.example/rss", 60), wantStatus: http.StatusNotFound},
+		{name: "never requested", method: "GET", path: verifyPath(unasked.ID.String(), "subscribe", unasked.Url, 60), wantStatus: http.StatusNotFound},
+		{name: "requested too long ago", method: "GET", path: verifyPath(stale.ID.String(), "subscribe", stale.Url, 60), wantStatus: http.StatusNotFound},
+		{name: "unsubscribe", method: "GET", path: verifyPath(id, "unsubscribe", fresh.Url, 0), wantStatus: http.StatusNotFound},
+		{name: "not a feed", method: "GET", path: verifyPath("nope", "subscribe", fresh.Url, 60), wantStatus: http.StatusNotFound},
+		{name: "subscribe", method: "GET", path: verifyPath(id, "subscribe", fresh.Url, 100*24*3600), want: []string{"c0ffee"}},
+		{name: "replayed", method: "GET", path: verifyPath(id, "subscribe", fresh.Url, 100*24*3600), wantStatus: http.StatusNotFound},
+	})
+	sub := db.webSub(fresh.ID)
+	if sub.State != "active" {
+		t.Fatalf("state %q after verification", sub.State)
+	}
+	if limit := time.Now().Add(websubLease + time.Minute); sub.LeaseExpiresAt.Time.After(limit) {
+		t.Errorf("lease until %v, longer than asked for", sub.LeaseExpiresAt.Time)
+	}
+	if s := db.webSub(stale.ID); s.State != "pending" {
+		t.Errorf("stale request state %q", s.State)
+	}
+
+	// A renewal is a new request, and the hub may grant less.
+	db.addWebSub(fresh, "https://hub.example/", "s", "active", time.Now())
+	replay(t, handler, []replayStep{
+		{name: "renewal", method: "GET", path: verifyPath(id, "subscribe", fresh.Url, 3600), want: []string{"c0ffee"}},
+		{name: "denied", method: "GET", path: "/websub/" + id + "?hub.mode=denied&hub.topic=" + fresh.Url + "&hub.reason=spam"},
+	})
+	if sub := db.webSub(fresh.ID); sub.State != "denied" {
+		t.Errorf("state %q after denial", sub.State)
+	}
+}
+
+const pushedFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Pushed</title><link>https://pushed.example/</link>
+<item><title>Hot off the hub</title><link>/posts/1?utm_source=hub</link></item>
+<item><title>Second</title><link>https://pushed.example/posts/2</link></item>
+</channel></rss>`
+
+func TestWebSubPush(t *testing.T) {
+	db, _, handler := newWebSubFixture(t)
+	alice := db.addUser("alice")
+	feed := db.addFeed(alice, "Pushed", "https://pushed.example/rss")
+	idle := db.addFeed(alice, "Idle", "https://idle.example/rss")
+	db.addWebSub(feed, "https://hub.example/", "s3cret", "active", time.Time{})
+	db.addWebSub(idle, "https://hub.example/", "s3cret", "pending", time.Now())
+
+	push := func(feedID, signature, body string) int {
+		req := httptest.NewRequest("POST", "/websub/"+feedID, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/rss+xml")
+		if signature != "" {
+			req.Header.Set("X-Hub-Signature", signature)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := push(idle.ID.String(), hubSignature("sha256", sha256.New, "s3cret", pushedFeed), pushedFeed); code != http.StatusNotFound {
+		t.Errorf("push to an inactive subscription: status %d", code)
+	}
+	for _, signature := range []string{"", hubSignature("sha256", sha256.New, "guess", pushedFeed)} {
+		if code := push(feed.ID.String(), signature, pushedFeed); code != http.StatusAccepted {
+			t.Errorf("unsigned push: status %d", code)
+		}
+	}
+	if len(db.posts) != 0 {
+		t.Fatalf("posts stored from an unverified push: %+v", db.posts)
+	}
+
+	signature := hubSignature("sha1", sha1.New, "s3cret", pushedFeed)
+	for range 2 {
+		if code := push(feed.ID.String(), signature, pushedFeed); code != http.StatusAccepted {
+			t.Errorf("signed push: status %d", code)
+		}
+	}
+	if len(db.posts) != 2 {
+		t.Fatalf("stored %d posts, want 2 (once each)", len(db.posts))
+	}
+	if got := db.posts[0]; got.Title != "Hot off the hub" || got.Url != "https://pushed.example/posts/1" || got.FeedID != feed.ID {
+		t.Errorf("first post %q %q", got.Title, got.Url)
+	}
+
+	garbage := "<html>not a feed</html>"
+	if code := push(feed.ID.String(), hubSignature("sha256", sha256.New, "s3cret", garbage), garbage); code != http.StatusAccepted {
+		t.Errorf("signed garbage: status %d", code)
+	}
+	if len(db.posts) != 2 {
+		t.Errorf("garbage push stored posts")
+	}
+}